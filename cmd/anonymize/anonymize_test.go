@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"gin-fleamarket/models"
+	"gin-fleamarket/testutil/factory"
+	"gin-fleamarket/testutil/testdb"
+	"testing"
+	"unicode/utf8"
+)
+
+// 削除済みのユーザーも含めて、メールアドレスとパスワードを書き換える
+func TestAnonymizeUsers(t *testing.T) {
+	db := testdb.Open(t)
+	users := factory.NewUserFactory(db)
+	active := users.Create(t, func(u *models.User) { u.Email = "taro@example.com" })
+	deleted := users.Create(t, func(u *models.User) { u.Email = "hanako@example.com" })
+	if err := db.Delete(deleted).Error; err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	count, err := anonymizeUsers(db)
+	if err != nil {
+		t.Fatalf("anonymizeUsers() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	for _, id := range []uint{active.ID, deleted.ID} {
+		var user models.User
+		if err := db.Unscoped().First(&user, id).Error; err != nil {
+			t.Fatalf("failed to find user %d: %v", id, err)
+		}
+		if want := fmt.Sprintf("user%d@example.invalid", id); user.Email != want {
+			t.Errorf("user %d Email = %q, want %q", id, user.Email, want)
+		}
+		if user.Password != anonymizedPassword {
+			t.Errorf("user %d Password = %q, want %q", id, user.Password, anonymizedPassword)
+		}
+	}
+}
+
+// 同じシードなら何度実行しても同じ説明文になり、文字数は変わらない
+func TestAnonymizeItemsIsDeterministic(t *testing.T) {
+	const description = "Size M、一度だけ着用しました。連絡先は090-1234-5678です"
+	descriptions := []string{}
+	for range 2 {
+		db := testdb.Open(t)
+		item := factory.NewItemFactory(db).Create(t, func(i *models.Item) { i.Description = description })
+		if _, err := anonymizeItems(db, 1); err != nil {
+			t.Fatalf("anonymizeItems() error = %v", err)
+		}
+		var anonymized models.Item
+		if err := db.First(&anonymized, item.ID).Error; err != nil {
+			t.Fatalf("failed to find item: %v", err)
+		}
+		descriptions = append(descriptions, anonymized.Description)
+	}
+	if descriptions[0] == description {
+		t.Errorf("description was not scrambled: %q", descriptions[0])
+	}
+	if descriptions[0] != descriptions[1] {
+		t.Errorf("descriptions differ between runs: %q, %q", descriptions[0], descriptions[1])
+	}
+	if got, want := utf8.RuneCountInString(descriptions[0]), utf8.RuneCountInString(description); got != want {
+		t.Errorf("length = %d, want %d", got, want)
+	}
+}
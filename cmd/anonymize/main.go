@@ -0,0 +1,112 @@
+// 本番DBのスナップショットをステージング用に匿名化する
+// IDや件数、文字数の分布はそのままに、個人を特定できる値だけを書き換える
+// 例: go run ./cmd/anonymize -yes
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"unicode"
+
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+const batchSize = 1000
+
+// ログインできないダミーのパスワード
+const anonymizedPassword = "!anonymized"
+
+func main() {
+	yes := flag.Bool("yes", false, "DBを書き換えることを確認する")
+	seed := flag.Uint64("seed", 1, "スクランブルに使う乱数のシード")
+	flag.Parse()
+
+	infra.Initialize()
+	if os.Getenv("APP_ENV") == "production" {
+		log.Fatal("refusing to anonymize a production database (APP_ENV=production)")
+	}
+	if !*yes {
+		log.Fatalf("this rewrites %s in place; re-run with -yes to continue", os.Getenv("DB_NAME"))
+	}
+	db := infra.SetupDB()
+
+	users, err := anonymizeUsers(db)
+	if err != nil {
+		log.Fatal("failed to anonymize users: ", err)
+	}
+	items, err := anonymizeItems(db, *seed)
+	if err != nil {
+		log.Fatal("failed to anonymize items: ", err)
+	}
+	fmt.Printf("anonymized %d users and %d items\n", users, items)
+}
+
+// メールアドレスはIDから作り直して一意性を保つ
+func anonymizeUsers(db *gorm.DB) (int, error) {
+	count := 0
+	var users []models.User
+	result := db.Unscoped().FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, v := range users {
+			err := db.Unscoped().Model(&v).UpdateColumns(map[string]interface{}{
+				"email":    fmt.Sprintf("user%d@example.invalid", v.ID),
+				"password": anonymizedPassword,
+			}).Error
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, result.Error
+}
+
+// 説明文は自由入力で個人情報が含まれうるため、文字の種類と長さを保ったままスクランブルする
+func anonymizeItems(db *gorm.DB, seed uint64) (int, error) {
+	count := 0
+	var items []models.Item
+	result := db.Unscoped().FindInBatches(&items, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, v := range items {
+			// 行ごとにシードを変えて、何度実行しても同じ結果になるようにする
+			r := rand.New(rand.NewPCG(seed, uint64(v.ID)))
+			err := db.Unscoped().Model(&v).UpdateColumns(map[string]interface{}{
+				"description": scramble(v.Description, r),
+			}).Error
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, result.Error
+}
+
+// 英字・数字・ひらがな・カタカナ・漢字を同じ種類のランダムな文字に置き換える
+func scramble(s string, r *rand.Rand) string {
+	runes := []rune(s)
+	for i, c := range runes {
+		switch {
+		case 'a' <= c && c <= 'z':
+			runes[i] = 'a' + r.Int32N(26)
+		case 'A' <= c && c <= 'Z':
+			runes[i] = 'A' + r.Int32N(26)
+		case '0' <= c && c <= '9':
+			runes[i] = '0' + r.Int32N(10)
+		case unicode.In(c, unicode.Hiragana):
+			runes[i] = 0x3041 + r.Int32N(0x3096-0x3041+1)
+		case unicode.In(c, unicode.Katakana):
+			runes[i] = 0x30A1 + r.Int32N(0x30FA-0x30A1+1)
+		case unicode.In(c, unicode.Han):
+			runes[i] = 0x4E00 + r.Int32N(0x9FFF-0x4E00+1)
+		}
+	}
+	return string(runes)
+}
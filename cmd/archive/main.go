@@ -0,0 +1,95 @@
+// 売り切れてから時間が経った商品を履歴テーブル(items_archive)に移す
+// 一覧や検索の対象になるitemsテーブルを小さく保つため、cronなどから定期的に実行する
+// 例: go run ./cmd/archive -days 365
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+// 1回のトランザクションで移す件数 (ロックを長く持たないように小分けにする)
+const batchSize = 1000
+
+// 売り切れてから更新のない商品を移す (保全中の商品は残す)
+// 短縮URLは商品の削除に合わせて消える
+// カラムの順序がitemsと履歴テーブルで異なっても移せるよう、%[1]sにカラム名を並べる
+const archiveBatch = `
+WITH moved AS (
+	DELETE FROM items
+	WHERE id IN (
+		SELECT id FROM items
+		WHERE sold_out AND NOT legal_hold AND updated_at < ?
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING %[1]s
+)
+INSERT INTO items_archive (%[1]s) SELECT %[1]s FROM moved`
+
+func main() {
+	days := flag.Int("days", 365, "売り切れてからこの日数が経った商品を移す")
+	dryRun := flag.Bool("dry-run", false, "移す件数を表示するだけにする")
+	flag.Parse()
+
+	infra.Initialize()
+	db := infra.SetupDB()
+	cutoff := time.Now().AddDate(0, 0, -*days)
+
+	if *dryRun {
+		var count int64
+		if err := db.Unscoped().Table("items").Where("sold_out AND NOT legal_hold AND updated_at < ?", cutoff).Count(&count).Error; err != nil {
+			log.Fatal("failed to count items: ", err)
+		}
+		fmt.Printf("%d items would be archived\n", count)
+		return
+	}
+
+	count, err := archiveItems(db, cutoff)
+	if err != nil {
+		log.Fatal("failed to archive items: ", err)
+	}
+	fmt.Printf("archived %d items\n", count)
+}
+
+func archiveItems(db *gorm.DB, cutoff time.Time) (int64, error) {
+	columns, err := itemColumns(db)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf(archiveBatch, columns)
+	var count int64
+	for {
+		result := db.Exec(query, cutoff, batchSize)
+		if result.Error != nil {
+			return count, result.Error
+		}
+		count += result.RowsAffected
+		if result.RowsAffected < batchSize {
+			return count, nil
+		}
+	}
+}
+
+// models.Itemに定義されたカラム名をカンマ区切りで返す (migrationsで履歴テーブルにも同じカラムを追加している)
+func itemColumns(db *gorm.DB) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&models.Item{}); err != nil {
+		return "", err
+	}
+	columns := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, v := range stmt.Schema.DBNames {
+		columns = append(columns, stmt.Quote(v))
+	}
+	return strings.Join(columns, ", "), nil
+}
@@ -0,0 +1,133 @@
+// 既存の行を少しずつ書き換えるバックフィルを実行する
+// 1バッチごとに進捗をbackfill_checkpointsに記録するため、中断しても続きから再開できる
+// 例: go run ./cmd/backfill -task item-metadata-defaults -batch 500 -rate 5
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"gin-fleamarket/infra"
+
+	"gorm.io/gorm"
+)
+
+// タスクごとの進捗 (最後に処理したID)
+type checkpoint struct {
+	Task      string `gorm:"primaryKey"`
+	LastID    uint   `gorm:"not null;default:0"`
+	Done      bool   `gorm:"not null;default:false"`
+	UpdatedAt time.Time
+}
+
+func (checkpoint) TableName() string {
+	return "backfill_checkpoints"
+}
+
+func main() {
+	name := flag.String("task", "", "実行するタスク")
+	batch := flag.Int("batch", 500, "1回のトランザクションで処理する行数")
+	rate := flag.Float64("rate", 10, "1秒あたりに処理するバッチ数の上限 (0で無制限)")
+	reset := flag.Bool("reset", false, "進捗を消して最初からやり直す")
+	flag.Parse()
+
+	t, ok := tasks[*name]
+	if !ok {
+		names := make([]string, 0, len(tasks))
+		for k := range tasks {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		log.Fatalf("unknown task %q (available: %v)", *name, names)
+	}
+
+	infra.Initialize()
+	db := infra.SetupDB()
+	if err := db.AutoMigrate(&checkpoint{}); err != nil {
+		log.Fatal("failed to migrate checkpoints: ", err)
+	}
+	if *reset {
+		if err := db.Delete(&checkpoint{Task: *name}).Error; err != nil {
+			log.Fatal("failed to reset checkpoint: ", err)
+		}
+	}
+
+	// Ctrl+Cでは処理中のバッチを終えてから止める
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	count, err := run(ctx, db, *name, t, *batch, *rate)
+	if err != nil {
+		log.Fatalf("backfill %s stopped after %d rows: %v", *name, count, err)
+	}
+	fmt.Printf("backfilled %d rows with %s\n", count, *name)
+}
+
+func run(ctx context.Context, db *gorm.DB, name string, t task, batchSize int, rate float64) (int64, error) {
+	progress := checkpoint{Task: name}
+	if err := db.FirstOrCreate(&progress, checkpoint{Task: name}).Error; err != nil {
+		return 0, err
+	}
+	if progress.Done {
+		log.Printf("%s has already finished (use -reset to run it again)", name)
+		return 0, nil
+	}
+
+	var throttle <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var count int64
+	for {
+		if throttle != nil {
+			select {
+			case <-ctx.Done():
+				return count, ctx.Err()
+			case <-throttle:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		var ids []uint
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// 論理削除された行も対象にする
+			if err := tx.Unscoped().Table(t.table).
+				Where("id > ?", progress.LastID).
+				Order("id").
+				Limit(batchSize).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				progress.Done = true
+			} else {
+				affected, err := t.apply(tx, ids)
+				if err != nil {
+					return err
+				}
+				count += affected
+				progress.LastID = ids[len(ids)-1]
+			}
+			// 書き換えと同じトランザクションで進捗を保存する
+			return tx.Save(&progress).Error
+		})
+		if err != nil {
+			return count, err
+		}
+		if progress.Done {
+			return count, nil
+		}
+		log.Printf("%s: processed up to id %d (%d rows updated)", name, progress.LastID, count)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "gorm.io/gorm"
+
+// バックフィルの内容
+// applyはidsの行だけを書き換え、更新した行数を返す (何度実行しても結果が変わらないようにする)
+type task struct {
+	table string
+	apply func(tx *gorm.DB, ids []uint) (int64, error)
+}
+
+// 新しいカラムを追加したときは、ここに既存の行を埋めるタスクを登録する
+var tasks = map[string]task{
+	// metadataのカラムを追加する前の商品はNULLのため、空のオブジェクトで埋める
+	"item-metadata-defaults": {
+		table: "items",
+		apply: func(tx *gorm.DB, ids []uint) (int64, error) {
+			result := tx.Exec("UPDATE items SET metadata = '{}' WHERE id IN ? AND metadata IS NULL", ids)
+			return result.RowsAffected, result.Error
+		},
+	},
+}
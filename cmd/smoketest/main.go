@@ -0,0 +1,187 @@
+// デプロイ後に実環境のAPIを一通り呼び出して疎通を確認するためのスモークテスト
+// 例: go run ./cmd/smoketest -base-url https://staging.example.com
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+type step struct {
+	name string
+	run  func(c *client) error
+}
+
+type client struct {
+	baseURL  string
+	http     *http.Client
+	itemId   string
+	itemName string
+}
+
+func main() {
+	baseURL := flag.String("base-url", envOrDefault("SMOKE_BASE_URL", "http://localhost:8080"), "テスト対象のベースURL")
+	timeout := flag.Duration("timeout", 10*time.Second, "1リクエストあたりのタイムアウト")
+	flag.Parse()
+
+	c := &client{baseURL: *baseURL, http: &http.Client{Timeout: *timeout}}
+	steps := []step{
+		{"ping", ping},
+		{"create item", createItem},
+		{"find item", findItem},
+		{"list items", listItems},
+		{"search items", searchItems},
+		{"patch item", patchItem},
+		{"delete item", deleteItem},
+		{"verify deleted", verifyDeleted},
+	}
+
+	passed := true
+	for _, s := range steps {
+		start := time.Now()
+		err := s.run(c)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			passed = false
+			fmt.Printf("FAIL  %-16s %8s  %v\n", s.name, elapsed, err)
+			// 後続のステップは前のステップの結果に依存するため打ち切る
+			break
+		}
+		fmt.Printf("PASS  %-16s %8s\n", s.name, elapsed)
+	}
+
+	if !passed {
+		fmt.Println("smoke test failed")
+		os.Exit(1)
+	}
+	fmt.Println("smoke test passed")
+}
+
+func ping(c *client) error {
+	_, err := c.do(http.MethodGet, "/ping", nil, http.StatusOK)
+	return err
+}
+
+func createItem(c *client) error {
+	c.itemName = fmt.Sprintf("smoketest-%d", time.Now().Unix())
+	body := map[string]interface{}{
+		"name":        c.itemName,
+		"price":       1000,
+		"description": "created by cmd/smoketest",
+	}
+	res, err := c.do(http.MethodPost, "/items", body, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	var created struct {
+		Data struct {
+			ID string
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(res, &created); err != nil {
+		return err
+	}
+	if created.Data.ID == "" {
+		return fmt.Errorf("created item has no ID")
+	}
+	c.itemId = created.Data.ID
+	return nil
+}
+
+func findItem(c *client) error {
+	_, err := c.do(http.MethodGet, "/items/"+c.itemId, nil, http.StatusOK)
+	return err
+}
+
+func listItems(c *client) error {
+	return c.expectItemInList("/items")
+}
+
+// 作成した商品の名前で検索して、結果に含まれることを確認する
+func searchItems(c *client) error {
+	return c.expectItemInList("/items/search?q=" + url.QueryEscape(c.itemName))
+}
+
+func (c *client) expectItemInList(path string) error {
+	res, err := c.do(http.MethodGet, path, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var list struct {
+		Data []struct {
+			ID string
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(res, &list); err != nil {
+		return err
+	}
+	for _, v := range list.Data {
+		if v.ID == c.itemId {
+			return nil
+		}
+	}
+	return fmt.Errorf("item %s not found in %s", c.itemId, path)
+}
+
+func patchItem(c *client) error {
+	body := map[string]interface{}{"price": 2000}
+	_, err := c.do(http.MethodPatch, "/items/"+c.itemId, body, http.StatusOK)
+	return err
+}
+
+func deleteItem(c *client) error {
+	_, err := c.do(http.MethodDelete, "/items/"+c.itemId, nil, http.StatusOK)
+	return err
+}
+
+func verifyDeleted(c *client) error {
+	_, err := c.do(http.MethodGet, "/items/"+c.itemId, nil, http.StatusNotFound)
+	return err
+}
+
+func (c *client) do(method string, path string, body interface{}, wantStatus int) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != wantStatus {
+		return nil, fmt.Errorf("%s %s: expected status %d, got %d: %s", method, path, wantStatus, res.StatusCode, b)
+	}
+	return b, nil
+}
+
+func envOrDefault(key string, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
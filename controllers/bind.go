@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-fleamarket/middlewares"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// 厳密な解釈で見つかった問題のあるフィールド
+type fieldError struct {
+	field   string
+	message string
+}
+
+func (e *fieldError) Error() string {
+	return e.message
+}
+
+// JSONのリクエストボディをバインドする
+// ルートにStrictBindingが設定されている場合は、未定義のキー・重複したキー・型の誤りを拒否する
+func bindJSON(ctx *gin.Context, obj interface{}) error {
+	if !middlewares.IsStrictBinding(ctx) {
+		return ctx.ShouldBindJSON(obj)
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return err
+	}
+	if err := checkKeys(json.NewDecoder(bytes.NewReader(body)), reflect.TypeOf(obj), ""); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(obj); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return &fieldError{field: typeError.Field, message: fmt.Sprintf("Invalid type for field %s", typeError.Field)}
+		}
+		return err
+	}
+	if decoder.More() {
+		return errors.New("Unexpected data after JSON body")
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// 重複したキーと、大文字小文字の違いも含めて定義されていないキーを探す
+// encoding/jsonは後のキーで上書きし、キー名を大文字小文字を区別せずに照合するため、事前にトークン単位で確認する
+func checkKeys(decoder *json.Decoder, t reflect.Type, path string) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		var fields map[string]reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = jsonFields(t)
+		}
+		keys := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if keys[key] {
+				return &fieldError{field: field, message: fmt.Sprintf("Duplicate field %s", field)}
+			}
+			keys[key] = true
+			// 値の型 (mapやinterface{}の場合はキーを自由に決められる)
+			var child reflect.Type
+			if fields != nil {
+				fieldType, ok := fields[key]
+				if !ok {
+					return &fieldError{field: field, message: fmt.Sprintf("Unknown field %s", field)}
+				}
+				child = fieldType
+			} else if t != nil && t.Kind() == reflect.Map {
+				child = t.Elem()
+			}
+			if err := checkKeys(decoder, child, field); err != nil {
+				return err
+			}
+		}
+	case '[':
+		var child reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			child = t.Elem()
+		}
+		for i := 0; decoder.More(); i++ {
+			if err := checkKeys(decoder, child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	// 閉じ括弧を読み飛ばす
+	_, err = decoder.Token()
+	return err
+}
+
+// 構造体のJSONのキー名と型
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// バインドに失敗したときのレスポンス (問題のあるフィールドが分かる場合は含める)
+func bindingErrorBody(err error) gin.H {
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		return gin.H{"error": fieldErr.message, "field": fieldErr.field}
+	}
+	return gin.H{"error": err.Error()}
+}
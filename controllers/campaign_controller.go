@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ICampaignController interface {
+	FindActive(ctx *gin.Context)
+	Create(ctx *gin.Context)
+}
+
+type CampaignController struct {
+	service services.ICampaignService
+}
+
+func NewCampaignController(service services.ICampaignService) ICampaignController {
+	return &CampaignController{service: service}
+}
+
+func (c *CampaignController) FindActive(ctx *gin.Context) {
+	campaigns, err := c.service.FindActive()
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+
+	responses := make([]dto.CampaignResponse, 0, len(*campaigns))
+	for _, v := range *campaigns {
+		responses = append(responses, dto.NewCampaignResponse(v))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": responses})
+}
+
+func (c *CampaignController) Create(ctx *gin.Context) {
+	var input dto.CreateCampaignInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	newCampaign, err := c.service.Create(input)
+	if err != nil {
+		if err.Error() == "Invalid discount" || err.Error() == "Invalid category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": dto.NewCampaignResponse(*newCampaign)})
+}
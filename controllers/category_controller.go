@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/services"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ICategoryController interface {
+	FindAll(ctx *gin.Context)
+	Create(ctx *gin.Context)
+	Move(ctx *gin.Context)
+	FindAttributeSchema(ctx *gin.Context)
+	UpdateAttributeSchema(ctx *gin.Context)
+	UpdateTranslations(ctx *gin.Context)
+	Merge(ctx *gin.Context)
+	RebuildItemCounts(ctx *gin.Context)
+}
+
+type CategoryController struct {
+	service services.ICategoryService
+	audit   *slog.Logger
+}
+
+func NewCategoryController(service services.ICategoryService) ICategoryController {
+	return &CategoryController{service: service, audit: infra.Logger("audit")}
+}
+
+func (c *CategoryController) FindAll(ctx *gin.Context) {
+	categories, err := c.service.FindAll()
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+
+	ctx.Header("Vary", "Accept-Language")
+	ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCategoryResponses(*categories, categoryLanguages(ctx))})
+}
+
+func (c *CategoryController) Create(ctx *gin.Context) {
+	var input dto.CreateCategoryInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	newCategory, err := c.service.Create(input)
+	if err != nil {
+		if err.Error() == "Invalid parent category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"data": dto.NewCategoryResponse(*newCategory, categoryLanguages(ctx))})
+}
+
+func (c *CategoryController) Move(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.MoveCategoryInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	movedCategory, err := c.service.Move(uint(categoryId), input)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Invalid parent category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCategoryResponse(*movedCategory, categoryLanguages(ctx))})
+}
+
+func (c *CategoryController) FindAttributeSchema(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	schema, err := c.service.AttributeSchema(uint(categoryId))
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": schema})
+}
+
+func (c *CategoryController) UpdateAttributeSchema(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.UpdateAttributeSchemaInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	updatedCategory, err := c.service.UpdateAttributeSchema(uint(categoryId), input)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Duplicate attribute key" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCategoryResponse(*updatedCategory, categoryLanguages(ctx))})
+}
+
+// カテゴリ名の翻訳を置き換える
+func (c *CategoryController) UpdateTranslations(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.UpdateTranslationsInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	updatedCategory, err := c.service.UpdateTranslations(uint(categoryId), input)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Invalid translation language" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCategoryResponse(*updatedCategory, categoryLanguages(ctx))})
+}
+
+// 重複したカテゴリを統合する (商品と子カテゴリは統合先に移り、古いIDは統合先に転送される)
+func (c *CategoryController) Merge(ctx *gin.Context) {
+	categoryId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.MergeCategoryInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	mergedCategory, err := c.service.Merge(uint(categoryId), input)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Invalid target category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	c.audit.Info("categories merged",
+		"source", categoryId,
+		"target", mergedCategory.ID,
+		"client_ip", ctx.ClientIP(),
+	)
+	ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCategoryResponse(*mergedCategory, categoryLanguages(ctx))})
+}
+
+// カテゴリごとの商品数の集計を作り直す (集計がずれた場合の修復用)
+func (c *CategoryController) RebuildItemCounts(ctx *gin.Context) {
+	if err := c.service.RebuildItemCounts(); err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,28 @@
+package controllers_test
+
+import (
+	"gin-fleamarket/testutil/golden"
+	"net/http"
+	"testing"
+)
+
+func TestCategoryEndpointsGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{name: "find_categories"},
+		{name: "find_categories_en", header: http.Header{"Accept-Language": {"en-US,en;q=0.9"}}},
+	}
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/categories", tt.header)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /categories status = %d, want %d\n%s", w.Code, http.StatusOK, w.Body)
+			}
+			golden.AssertJSON(t, tt.name, w.Body.Bytes(), volatileKeys...)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// HTTPの条件付きリクエスト (ETag / Last-Modified) の処理
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"gin-fleamarket/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 商品のETag (更新日時が変わるたびに変わる)
+// 連番のIDが分からないようにハッシュ化する
+func itemETag(item *models.Item) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d-%d", item.ID, item.UpdatedAt.UnixNano())))
+	return fmt.Sprintf("\"%x\"", sum[:8])
+}
+
+// レスポンスにETagとLast-Modifiedを設定する
+func setItemValidators(ctx *gin.Context, item *models.Item) {
+	ctx.Header("ETag", itemETag(item))
+	ctx.Header("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+func hasPreconditions(ctx *gin.Context) bool {
+	return ctx.GetHeader("If-Match") != "" || ctx.GetHeader("If-Unmodified-Since") != ""
+}
+
+// If-Match / If-Unmodified-Since を満たすかどうか
+// If-Matchがある場合はIf-Unmodified-Sinceは無視する (RFC 9110)
+func preconditionsMet(ctx *gin.Context, item *models.Item) bool {
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" {
+		if strings.TrimSpace(ifMatch) == "*" {
+			return true
+		}
+		etag := itemETag(item)
+		for _, v := range strings.Split(ifMatch, ",") {
+			if strings.TrimSpace(v) == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ifUnmodifiedSince := ctx.GetHeader("If-Unmodified-Since"); ifUnmodifiedSince != "" {
+		since, err := time.Parse(http.TimeFormat, ifUnmodifiedSince)
+		if err != nil {
+			// 解釈できない日付は無視する
+			return true
+		}
+		// HTTP-dateは秒単位のため切り捨てて比較する
+		return !item.UpdatedAt.Truncate(time.Second).After(since)
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"gin-fleamarket/infra"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IConfigController interface {
+	FindConfig(ctx *gin.Context)
+	Reload(ctx *gin.Context)
+}
+
+// 障害調査用に、実行中のプロセスが使っている設定を返す
+type ConfigController struct {
+	// 有効になっている機能 (設定の組み合わせや実行モードで決まるもの)
+	features map[string]bool
+	// 接続している外部のサービスと、その実装
+	integrations map[string]string
+	reloader     *infra.ConfigReloader
+	audit        *slog.Logger
+}
+
+func NewConfigController(features map[string]bool, integrations map[string]string, reloader *infra.ConfigReloader) IConfigController {
+	return &ConfigController{features: features, integrations: integrations, reloader: reloader, audit: infra.Logger("audit")}
+}
+
+func (c *ConfigController) FindConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"settings":     infra.LoadedSettings(),
+		"features":     c.features,
+		"integrations": c.integrations,
+	}})
+}
+
+// 再起動せずに設定を読み直す (SIGHUPを送った場合と同じ)
+// 新しい設定が不正な場合は、実行中の設定のまま422を返す
+func (c *ConfigController) Reload(ctx *gin.Context) {
+	result, err := c.reloader.Reload()
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid config: " + err.Error()})
+		return
+	}
+	c.audit.Info("config reloaded",
+		"applied", result.Applied,
+		"restart_required", result.RestartRequired,
+		"client_ip", ctx.ClientIP(),
+	)
+	ctx.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"fmt"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/services"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 埋め込みの既定のサイズ
+const (
+	embedWidth  = 400
+	embedHeight = 120
+)
+
+type IEmbedController interface {
+	Item(ctx *gin.Context)
+}
+
+type EmbedController struct {
+	service services.IItemService
+	idCodec infra.IIDCodec
+	baseURL string
+}
+
+func NewEmbedController(service services.IItemService, idCodec infra.IIDCodec, baseURL string) IEmbedController {
+	return &EmbedController{service: service, idCodec: idCodec, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// ブログなどに商品を埋め込むためのoEmbed形式のレスポンスを返す
+func (c *EmbedController) Item(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.EmbedInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := c.service.FindById(itemId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+
+	width, height := embedWidth, embedHeight
+	if input.MaxWidth > 0 && input.MaxWidth < width {
+		width = input.MaxWidth
+	}
+	if input.MaxHeight > 0 && input.MaxHeight < height {
+		height = input.MaxHeight
+	}
+	price := item.Price
+	if item.DiscountedPrice != nil {
+		price = *item.DiscountedPrice
+	}
+	url := c.baseURL + "/items/" + c.idCodec.Encode(item.ID)
+	snippet := fmt.Sprintf(
+		`<div class="fleamarket-embed" style="width:%dpx;height:%dpx"><a href="%s" target="_blank" rel="noopener">%s</a><p>¥%d</p></div>`,
+		width, height, html.EscapeString(url), html.EscapeString(item.Name), price,
+	)
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, dto.EmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		Title:        item.Name,
+		ProviderName: "gin-fleamarket",
+		ProviderURL:  c.baseURL,
+		HTML:         snippet,
+		Width:        width,
+		Height:       height,
+		Price:        price,
+		URL:          url,
+	})
+}
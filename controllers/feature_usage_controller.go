@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IFeatureUsageController interface {
+	FindUsage(ctx *gin.Context)
+}
+
+// 機能を残すか・改善するかを判断するための、ルートと機能ごとの利用回数
+type FeatureUsageController struct {
+	service services.IFeatureUsageService
+}
+
+func NewFeatureUsageController(service services.IFeatureUsageService) IFeatureUsageController {
+	return &FeatureUsageController{service: service}
+}
+
+func (c *FeatureUsageController) FindUsage(ctx *gin.Context) {
+	var input dto.FeatureUsageInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := c.service.FindUsage(input)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// フィードに含める新着商品の件数
+const feedSize = 50
+
+// フィードリーダーが取得しに来る間隔の目安
+const feedMaxAge = 5 * time.Minute
+
+type IFeedController interface {
+	Items(ctx *gin.Context)
+}
+
+type FeedController struct {
+	service services.IItemService
+	idCodec infra.IIDCodec
+	// 商品ページへのリンクに使う公開URL
+	baseURL string
+}
+
+func NewFeedController(service services.IItemService, idCodec infra.IIDCodec, baseURL string) IFeedController {
+	return &FeedController{service: service, idCodec: idCodec, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Atom (RFC 4287) の要素
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Link      atomLink `xml:"link"`
+	Summary   string   `xml:"summary"`
+}
+
+func (c *FeedController) Items(ctx *gin.Context) {
+	var input dto.FeedInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := c.service.FindRecent(input.CategoryID, feedSize)
+	if err != nil {
+		if err.Error() == "Invalid category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+
+	self := c.baseURL + ctx.Request.URL.RequestURI()
+	feed := atomFeed{
+		Title: "新着の商品",
+		ID:    self,
+		Links: []atomLink{{Href: self, Rel: "self"}},
+	}
+	var updated time.Time
+	for _, v := range *items {
+		link := c.baseURL + "/items/" + c.idCodec.Encode(v.ID)
+		price := v.Price
+		if v.DiscountedPrice != nil {
+			price = *v.DiscountedPrice
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     v.Name,
+			ID:        link,
+			Published: v.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   v.UpdatedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: link, Rel: "alternate"},
+			Summary:   fmt.Sprintf("¥%d %s", price, v.Description),
+		})
+		if v.UpdatedAt.After(updated) {
+			updated = v.UpdatedAt
+		}
+	}
+	// 必須の要素のため、商品がない場合はUNIXエポックにする (ETagを変えないため現在時刻は使わない)
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	body, err := xml.Marshal(feed)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	// 内容が変わっていなければ304を返して、リーダーからの定期的な取得を軽くする
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf("\"%x\"", sum[:8])
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedMaxAge.Seconds())))
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/atom+xml; charset=utf-8", body)
+}
@@ -0,0 +1,159 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var camelCasePattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// X-Field-Naming: legacy では以前の名前に置き換わるフィールド (一部)
+var renamedFields = []string{"id", "createdAt", "updatedAt", "name", "price", "soldOut", "categoryId", "parentId", "discountedPrice"}
+
+var fieldNamingPaths = []string{"/items/1", "/items", "/categories", "/campaigns/active"}
+
+// レスポンスのフィールド名がcamelCaseに揃っていることを確認する
+func TestFieldNamingCamel(t *testing.T) {
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	for _, path := range fieldNamingPaths {
+		t.Run(path, func(t *testing.T) {
+			w := s.do(http.MethodGet, path, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d\n%s", path, w.Code, http.StatusOK, w.Body)
+			}
+			for _, key := range keysOf(t, w.Body.Bytes()) {
+				if !camelCasePattern.MatchString(key) {
+					t.Errorf("GET %s: field %q is not camelCase", path, key)
+				}
+			}
+			if got := w.Header().Get("Deprecation"); got != "" {
+				t.Errorf("GET %s: Deprecation = %q, want empty", path, got)
+			}
+		})
+	}
+}
+
+// X-Field-Naming: legacy では以前のフィールド名で返す
+func TestFieldNamingLegacy(t *testing.T) {
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	for _, path := range fieldNamingPaths {
+		t.Run(path, func(t *testing.T) {
+			w := s.do(http.MethodGet, path, http.Header{"X-Field-Naming": {"legacy"}})
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d\n%s", path, w.Code, http.StatusOK, w.Body)
+			}
+			keys := map[string]bool{}
+			for _, key := range keysOf(t, w.Body.Bytes()) {
+				keys[key] = true
+			}
+			for _, key := range renamedFields {
+				if keys[key] {
+					t.Errorf("GET %s: field %q is not renamed", path, key)
+				}
+			}
+			if !keys["ID"] {
+				t.Errorf("GET %s: field \"ID\" not found", path)
+			}
+			if got := w.Header().Get("Deprecation"); got != "true" {
+				t.Errorf("GET %s: Deprecation = %q, want %q", path, got, "true")
+			}
+		})
+	}
+}
+
+// HEADはボディを返さず、GETと同じヘッダーを返す
+// Content-Lengthを返す場合は、FieldNamingなどで書き換えた後のボディの長さと一致する
+func TestHeadMatchesGet(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+	}{
+		{name: "item", path: "/items/1"},
+		{name: "item legacy", path: "/items/1", header: http.Header{"X-Field-Naming": {"legacy"}}},
+		{name: "items legacy", path: "/items?limit=1", header: http.Header{"X-Field-Naming": {"legacy"}}},
+		{name: "not found", path: "/items/999"},
+		{name: "not found legacy", path: "/items/999", header: http.Header{"X-Field-Naming": {"legacy"}}},
+	}
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get, getBody := request(t, server, http.MethodGet, tt.path, tt.header)
+			head, headBody := request(t, server, http.MethodHead, tt.path, tt.header)
+			if head.StatusCode != get.StatusCode {
+				t.Errorf("HEAD status = %d, GET status = %d", head.StatusCode, get.StatusCode)
+			}
+			if len(headBody) != 0 {
+				t.Errorf("HEAD body = %q, want empty", headBody)
+			}
+			if head.ContentLength >= 0 && head.ContentLength != int64(len(getBody)) {
+				t.Errorf("HEAD Content-Length = %d, GET body length = %d", head.ContentLength, len(getBody))
+			}
+			for _, key := range []string{"Content-Type", "Vary", "Deprecation", "ETag"} {
+				if head.Header.Get(key) != get.Header.Get(key) {
+					t.Errorf("HEAD %s = %q, GET %s = %q", key, head.Header.Get(key), key, get.Header.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func request(t *testing.T, server *httptest.Server, method string, path string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return res, body
+}
+
+// レスポンスに含まれるフィールド名 (利用者が決めるattributesとmetadataの中身は除く)
+func keysOf(t *testing.T, body []byte) []string {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, body)
+	}
+	keys := []string{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				keys = append(keys, key)
+				if key == "attributes" || key == "metadata" || key == "Attributes" || key == "Metadata" {
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(value)
+	return keys
+}
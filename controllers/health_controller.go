@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 依存先ごとの確認にかける時間の上限
+const readinessTimeout = 2 * time.Second
+
+type IHealthController interface {
+	Readyz(ctx *gin.Context)
+}
+
+// ロードバランサーなどからの、リクエストを受けられる状態かの確認
+type HealthController struct {
+	// 依存先の名前ごとの確認
+	checks map[string]func(ctx context.Context) error
+}
+
+func NewHealthController(checks map[string]func(ctx context.Context) error) IHealthController {
+	return &HealthController{checks: checks}
+}
+
+type dependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// 全ての依存先を並行して確認し、1つでも失敗していれば503を返す
+func (c *HealthController) Readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	dependencies := map[string]dependencyStatus{}
+	ready := true
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			begin := time.Now()
+			err := check(checkCtx)
+			status := dependencyStatus{Status: "ok", LatencyMs: time.Since(begin).Milliseconds()}
+			if err != nil {
+				status.Status = "error"
+				status.Error = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			dependencies[name] = status
+			ready = ready && err == nil
+		}()
+	}
+	wg.Wait()
+
+	ctx.Header("Cache-Control", "no-store")
+	if !ready {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "dependencies": dependencies})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "dependencies": dependencies})
+}
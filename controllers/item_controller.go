@@ -1,112 +1,479 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
 	"gin-fleamarket/services"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// アップロードできる画像の最大サイズ (バイト)
+const maxImageUploadBytes = 10 << 20
+
 type IItemController interface {
 	FindAll(ctx *gin.Context)
 	FindById(ctx *gin.Context)
 	Create(ctx *gin.Context)
 	Update(ctx *gin.Context)
+	Patch(ctx *gin.Context)
 	Delete(ctx *gin.Context)
+	MarkSoldExternally(ctx *gin.Context)
+	UpdateLegalHold(ctx *gin.Context)
+	UploadImage(ctx *gin.Context)
+	Search(ctx *gin.Context)
+	Suggest(ctx *gin.Context)
+	SuggestPrice(ctx *gin.Context)
+	SearchByImage(ctx *gin.Context)
+	Stream(ctx *gin.Context)
 }
 
 type ItemController struct {
 	service services.IItemService
+	idCodec infra.IIDCodec
+	audit   *slog.Logger
 }
 
-func NewItemController(service services.IItemService) IItemController {
-	return &ItemController{service: service}
+func NewItemController(service services.IItemService, idCodec infra.IIDCodec) IItemController {
+	return &ItemController{service: service, idCodec: idCodec, audit: infra.Logger("audit")}
 }
 
 func (c *ItemController) FindAll(ctx *gin.Context) {
-	items, err := c.service.FindAll()
+	var input dto.FindItemsInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input.Attributes = ctx.QueryMap("attributes")
+	input.Metadata = ctx.QueryMap("metadata")
+
+	list, err := c.service.FindAll(input)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		if err.Error() == "Invalid category" || err.Error() == "Invalid metadata" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": items})
+	if list.Meta != nil {
+		setPaginationLinks(ctx, list.Meta)
+	}
+	c.respondItems(ctx, http.StatusOK, list.Each, list.Meta)
 }
 
 func (c *ItemController) FindById(ctx *gin.Context) {
-	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
-	item, err := c.service.FindById(uint(itemId))
+	item, err := c.service.FindById(itemId)
 	if err != nil {
 		if err.Error() == "Item not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"data": item})
+	setItemValidators(ctx, item)
+	respond(ctx, http.StatusOK, gin.H{"data": c.toResponse(ctx, item)})
 }
 
 func (c *ItemController) Create(ctx *gin.Context) {
 	var input dto.CreateItemInput
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindJSON(ctx, &input); err != nil {
+		respond(ctx, http.StatusBadRequest, bindingErrorBody(err))
 		return
 	}
 
 	newItem, err := c.service.Create(input)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err.Error() == "Invalid category" || err.Error() == "Invalid attributes" || err.Error() == "Invalid metadata" || err.Error() == "Invalid item" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusCreated, gin.H{"data": newItem})
+	respond(ctx, http.StatusCreated, gin.H{"data": c.toResponse(ctx, newItem)})
 }
 
 func (c *ItemController) Update(ctx *gin.Context) {
-	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	c.update(ctx, c.service.Update)
+}
+
+func (c *ItemController) Patch(ctx *gin.Context) {
+	c.update(ctx, c.service.Patch)
+}
+
+func (c *ItemController) update(ctx *gin.Context, update func(itemId uint, updateItemInput dto.UpdateItemInput, precondition services.ItemPrecondition) (*models.Item, error)) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 	var input dto.UpdateItemInput
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindJSON(ctx, &input); err != nil {
+		respond(ctx, http.StatusBadRequest, bindingErrorBody(err))
 		return
 	}
 
-	updatedItem, err := c.service.Update(uint(itemId), input)
+	updatedItem, err := update(itemId, input, preconditionOf(ctx))
 	if err != nil {
 		if err.Error() == "Item not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		if err.Error() == "Precondition failed" {
+			c.respondPreconditionFailed(ctx, itemId)
+			return
+		}
+		if err.Error() == "Invalid category" || err.Error() == "Invalid attributes" || err.Error() == "Invalid metadata" || err.Error() == "Invalid item" || err.Error() == "Invalid alt text" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"data": updatedItem})
+	setItemValidators(ctx, updatedItem)
+	respond(ctx, http.StatusOK, gin.H{"data": c.toResponse(ctx, updatedItem)})
 }
 
 func (c *ItemController) Delete(ctx *gin.Context) {
-	itemId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	err = c.service.Delete(itemId, preconditionOf(ctx))
+	if err != nil {
+		if err.Error() == "Item not found" {
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Precondition failed" {
+			c.respondPreconditionFailed(ctx, itemId)
+			return
+		}
+		if err.Error() == "Item is under legal hold" {
+			respond(ctx, http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// プラットフォーム外で売れた商品の出品を終了する
+func (c *ItemController) MarkSoldExternally(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	closedItem, err := c.service.MarkSoldExternally(itemId, preconditionOf(ctx))
+	if err != nil {
+		if err.Error() == "Item not found" {
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Precondition failed" {
+			c.respondPreconditionFailed(ctx, itemId)
+			return
+		}
+		if err.Error() == "Item already sold out" {
+			respond(ctx, http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	setItemValidators(ctx, closedItem)
+	respond(ctx, http.StatusOK, gin.H{"data": c.toResponse(ctx, closedItem)})
+}
+
+// 商品を保全の対象にする (または解除する)
+func (c *ItemController) UpdateLegalHold(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.UpdateLegalHoldInput
+	if err := bindJSON(ctx, &input); err != nil {
+		respond(ctx, http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+
+	updatedItem, err := c.service.SetLegalHold(itemId, *input.LegalHold)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	c.audit.Info("legal hold changed",
+		"target", updatedItem.ID,
+		"legal_hold", updatedItem.LegalHold,
+		"client_ip", ctx.ClientIP(),
+	)
+	respond(ctx, http.StatusOK, gin.H{"data": dto.LegalHoldResponse{ID: c.idCodec.Encode(updatedItem.ID), LegalHold: updatedItem.LegalHold}})
+}
+
+func (c *ItemController) UploadImage(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	image, ok := openImage(ctx)
+	if !ok {
 		return
 	}
+	defer image.Close()
 
-	err = c.service.Delete(uint(itemId))
+	// 代替テキストは省略できる (省略した場合は商品名を使う)
+	updatedItem, err := c.service.SetImage(itemId, image, ctx.PostForm("altText"))
 	if err != nil {
 		if err.Error() == "Item not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respond(ctx, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Invalid image" || err.Error() == "Invalid alt text" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Image too large" {
+			respond(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	respond(ctx, http.StatusOK, gin.H{"data": c.toResponse(ctx, updatedItem)})
+}
+
+func (c *ItemController) Search(ctx *gin.Context) {
+	var input dto.SearchItemsInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.service.Search(input)
+	if err != nil {
+		if err.Error() == "Invalid query" || err.Error() == "Invalid category" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	responses := make([]dto.ItemResponse, 0, len(result.Items))
+	for _, v := range result.Items {
+		response := c.toResponse(ctx, &v)
+		if h, ok := result.Highlights[v.ID]; ok {
+			response.Highlight = &h
+		}
+		responses = append(responses, response)
+	}
+	body := gin.H{"data": responses, "facets": result.Facets}
+	if result.DidYouMean != nil {
+		body["didYouMean"] = *result.DidYouMean
+	}
+	respond(ctx, http.StatusOK, body)
+}
+
+// 検索欄の入力補完 (入力のたびに呼ばれるため、短い時間キャッシュさせる)
+func (c *ItemController) Suggest(ctx *gin.Context) {
+	var input dto.SuggestItemsInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	completions, items, err := c.service.Suggest(input.Q)
+	if err != nil {
+		if err.Error() == "Invalid query" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	response := dto.SuggestResponse{Completions: completions, Items: make([]dto.ItemSuggestion, 0, len(*items))}
+	for _, v := range *items {
+		response.Items = append(response.Items, dto.ItemSuggestion{ID: c.idCodec.Encode(v.ID), Name: v.Name})
+	}
+	ctx.Header("Cache-Control", "public, max-age=60")
+	respond(ctx, http.StatusOK, gin.H{"data": response})
+}
+
+// 出品時の参考に、最近売れた似た商品の価格帯を返す
+func (c *ItemController) SuggestPrice(ctx *gin.Context) {
+	var input dto.PriceSuggestionInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestion, err := c.service.SuggestPrice(input)
+	if err != nil {
+		if err.Error() == "Invalid name" || err.Error() == "Invalid category" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	respond(ctx, http.StatusOK, gin.H{"data": suggestion})
+}
+
+func (c *ItemController) SearchByImage(ctx *gin.Context) {
+	image, ok := openImage(ctx)
+	if !ok {
+		return
+	}
+	defer image.Close()
+
+	items, err := c.service.SearchByImage(image)
+	if err != nil {
+		if err.Error() == "Invalid image" {
+			respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "Image too large" {
+			respond(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	c.respondItems(ctx, http.StatusOK, itemsOf(items), nil)
+}
+
+// フォームのimageを開く (開けない場合はレスポンスを返してfalse)
+// 上限を超える本文は読み込まずに拒否する
+func openImage(ctx *gin.Context) (multipart.File, bool) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImageUploadBytes)
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			respond(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": "Image too large"})
+			return nil, false
+		}
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Image is required"})
+		return nil, false
+	}
+	image, err := file.Open()
+	if err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": "Image is required"})
+		return nil, false
+	}
+	return image, true
+}
+
+func (c *ItemController) Stream(ctx *gin.Context) {
+	var input dto.StreamItemsInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		respond(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
 	ctx.Status(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Writer)
+	err := c.service.Stream(input, func(items []models.Item) error {
+		// クライアントが切断した場合は読み込みを打ち切る
+		if err := ctx.Request.Context().Err(); err != nil {
+			return err
+		}
+		for _, v := range items {
+			if err := encoder.Encode(c.toResponse(ctx, &v)); err != nil {
+				return err
+			}
+		}
+		// バッチごとに送り出し、書き込みが詰まっている間は次のバッチを読まない
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// ヘッダーは送信済みのため、ステータスコードは変更できない
+		ctx.Error(err)
+	}
+}
+
+// 条件付きリクエストの場合は現在の商品と照合し、満たさない場合は412を返す
+// If-Match / If-Unmodified-Since を書き込みの条件にする (条件がない場合はnil)
+// 確認と書き込みの間に他の更新が入った場合も412にするため、確認はサービスの書き込みの中でおこなう
+func preconditionOf(ctx *gin.Context) services.ItemPrecondition {
+	if !hasPreconditions(ctx) {
+		return nil
+	}
+	return func(item *models.Item) bool {
+		return preconditionsMet(ctx, item)
+	}
+}
+
+// 取得し直さずに再試行できるよう、現在の商品の検証子を付けて412を返す
+func (c *ItemController) respondPreconditionFailed(ctx *gin.Context, itemId uint) {
+	if item, err := c.service.FindById(itemId); err == nil {
+		setItemValidators(ctx, item)
+	}
+	respond(ctx, http.StatusPreconditionFailed, gin.H{"error": "Precondition failed"})
+}
+
+// 商品のIDを公開用の表記に変換したレスポンスを作る
+func (c *ItemController) toResponse(ctx *gin.Context, item *models.Item) dto.ItemResponse {
+	response := dto.ItemResponse{
+		ID:            c.idCodec.Encode(item.ID),
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+		Name:          item.Name,
+		Price:         item.Price,
+		Description:   item.Description,
+		SoldOut:       item.SoldOut,
+		ImageHash:     item.ImageHash,
+		ImageAltText:  item.AltText(),
+		CategoryID:    item.CategoryID,
+		Attributes:    item.Attributes,
+		Metadata:      item.Metadata,
+		ClosureReason: item.ClosureReason,
+		ClosedAt:      item.ClosedAt,
+		Breadcrumb:    dto.NewCategoryResponses(item.Breadcrumb, categoryLanguages(ctx)),
+
+		DiscountedPrice: item.DiscountedPrice,
+		CampaignID:      item.CampaignID,
+	}
+	if item.DeletedAt.Valid {
+		response.DeletedAt = &item.DeletedAt.Time
+	}
+	newPriceFormatter(ctx).apply(&response)
+	return response
 }
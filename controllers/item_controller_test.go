@@ -0,0 +1,265 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/pb"
+	"gin-fleamarket/testutil/factory"
+	"gin-fleamarket/testutil/golden"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// カテゴリ・属性・キャンペーンを含む商品を登録する
+func seedCatalog(t *testing.T, s *testServer) {
+	t.Helper()
+	categories := factory.NewCategoryFactory(s.db)
+	fashion := categories.Create(t, nil, func(c *models.Category) {
+		c.Name = "ファッション"
+		c.Translations = models.LocalizedNames{"en": "Fashion"}
+	})
+	shirts := categories.Create(t, fashion, func(c *models.Category) {
+		c.Name = "シャツ"
+		c.Position = 1
+	})
+	categories.Create(t, fashion, func(c *models.Category) {
+		c.Name = "靴"
+	})
+
+	items := factory.NewItemFactory(s.db)
+	items.Create(t, func(i *models.Item) {
+		i.Name = "白いシャツ"
+		i.Price = 2500
+		i.Description = "一度だけ着用しました"
+		i.CategoryID = &shirts.ID
+		i.Attributes = models.JSONMap{"size": "M"}
+		i.Metadata = models.JSONMap{"condition": "like_new"}
+	})
+	items.Create(t, func(i *models.Item) {
+		i.Name = "古い本"
+		i.Price = 300
+		i.Description = "表紙に傷があります"
+		i.SoldOut = true
+	})
+
+	campaign := models.Campaign{
+		Name:            "春のセール",
+		StartsAt:        testNow.Add(-24 * time.Hour),
+		EndsAt:          testNow.Add(24 * time.Hour),
+		CategoryID:      &fashion.ID,
+		DiscountPercent: 10,
+	}
+	if err := s.db.Create(&campaign).Error; err != nil {
+		t.Fatalf("failed to create campaign: %v", err)
+	}
+}
+
+func TestItemEndpointsGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		status int
+	}{
+		{name: "find_items", path: "/items", status: http.StatusOK},
+		{name: "find_items_page", path: "/items?page=2&limit=1", status: http.StatusOK},
+		{name: "find_item", path: "/items/1", status: http.StatusOK},
+		{name: "find_item_en", path: "/items/1", header: http.Header{"Accept-Language": {"en"}}, status: http.StatusOK},
+		{name: "find_item_not_found", path: "/items/999", status: http.StatusNotFound},
+	}
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, tt.path, tt.header)
+			if w.Code != tt.status {
+				t.Fatalf("GET %s status = %d, want %d\n%s", tt.path, w.Code, tt.status, w.Body)
+			}
+			golden.AssertJSON(t, tt.name, w.Body.Bytes(), volatileKeys...)
+		})
+	}
+}
+
+// imageフィールドにdataを入れたmultipartのリクエスト
+func imageRequest(t *testing.T, path string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "image.png")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		t.Fatalf("failed to write form file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatalf("failed to close form: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestSearchByImageLimits(t *testing.T) {
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		status int
+	}{
+		{name: "small image", data: small.Bytes(), status: http.StatusOK},
+		{name: "not an image", data: []byte("not an image"), status: http.StatusBadRequest},
+		{name: "body too large", data: make([]byte, 11<<20), status: http.StatusRequestEntityTooLarge},
+	}
+	s := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, imageRequest(t, "/items/search/by-image", tt.data))
+			if w.Code != tt.status {
+				t.Errorf("POST /items/search/by-image status = %d, want %d\n%s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+// protobufでもJSONと同じ公開用のIDを返す
+func TestFindItemsProtobufIDs(t *testing.T) {
+	idCodec := infra.NewIDCodec("salt")
+	s := newTestServerWithIDCodec(t, idCodec)
+	seedCatalog(t, s)
+
+	w := s.do(http.MethodGet, "/items", nil)
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	w = s.do(http.MethodGet, "/items", http.Header{"Accept": {"application/x-protobuf"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /items status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var items pb.ItemList
+	if err := proto.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("invalid protobuf: %v", err)
+	}
+	if len(items.Items) != len(list.Data) {
+		t.Fatalf("protobuf items = %d, JSON items = %d", len(items.Items), len(list.Data))
+	}
+	for i, v := range items.Items {
+		if v.Id != list.Data[i].ID {
+			t.Errorf("items[%d].id = %q, want %q", i, v.Id, list.Data[i].ID)
+		}
+		if id, err := idCodec.Decode(v.Id); err != nil || v.Id == fmt.Sprint(id) {
+			t.Errorf("items[%d].id = %q, want an encoded ID", i, v.Id)
+		}
+	}
+}
+
+func TestCreateItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{name: "valid", body: `{"name":"白いシャツ","price":1000}`, status: http.StatusCreated},
+		// バインディングは通るが、BeforeSaveで拒否される
+		{name: "blank name", body: `{"name":"   ","price":100}`, status: http.StatusBadRequest, error: "Invalid item"},
+	}
+	s := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("POST /items status = %d, want %d\n%s", w.Code, tt.status, w.Body)
+			}
+			if tt.error == "" {
+				return
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if body.Error != tt.error {
+				t.Errorf("error = %q, want %q", body.Error, tt.error)
+			}
+		})
+	}
+}
+
+func TestConditionalItemWrites(t *testing.T) {
+	s := newTestServer(t)
+	seedCatalog(t, s)
+	etag := s.do(http.MethodGet, "/items/1", nil).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET /items/1 did not return an ETag")
+	}
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/items/1", strings.NewReader(`{"price":1500}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 1人目の書き込みは通り、ETagが変わる
+	w := patch(etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first PATCH status = %d, want %d\n%s", w.Code, http.StatusOK, w.Body)
+	}
+	current := w.Header().Get("ETag")
+	if current == "" || current == etag {
+		t.Fatalf("ETag after PATCH = %q, want a new one (was %q)", current, etag)
+	}
+
+	// 同じETagで書き込んだ2人目は412になり、現在のETagを受け取る
+	tests := []struct {
+		name string
+		do   func() *httptest.ResponseRecorder
+	}{
+		{name: "patch", do: func() *httptest.ResponseRecorder { return patch(etag) }},
+		{name: "delete", do: func() *httptest.ResponseRecorder {
+			return s.do(http.MethodDelete, "/items/1", http.Header{"If-Match": {etag}})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.do()
+			if w.Code != http.StatusPreconditionFailed {
+				t.Fatalf("status = %d, want %d\n%s", w.Code, http.StatusPreconditionFailed, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != current {
+				t.Errorf("ETag = %q, want %q", got, current)
+			}
+		})
+	}
+	if w := s.do(http.MethodGet, "/items/1", nil); w.Header().Get("ETag") != current {
+		t.Errorf("item changed after rejected writes: ETag = %q, want %q", w.Header().Get("ETag"), current)
+	}
+}
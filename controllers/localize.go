@@ -0,0 +1,67 @@
+// Accept-Languageに合わせた価格の表示用の整形とカテゴリ名の言語の選択
+
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// 先頭が既定の言語 (Accept-Languageがない場合や一致しない場合)
+var priceLanguages = language.NewMatcher([]language.Tag{
+	language.Japanese,
+	language.English,
+	language.Chinese,
+	language.Korean,
+	language.German,
+	language.French,
+})
+
+// カテゴリ名に使う言語の優先順 (Accept-Languageの順。en-USなどは地域を除いて扱う)
+func categoryLanguages(ctx *gin.Context) []string {
+	tags, _, _ := language.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+	languages := []string{}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		languages = append(languages, base.String())
+	}
+	return languages
+}
+
+// 価格は全て円のため通貨は変えず、記号と桁区切りだけを言語に合わせる (例: ￥1,000 / ¥1.000)
+type priceFormatter struct {
+	printer *message.Printer
+	symbol  string
+}
+
+// ?formatPrices=false の場合はnil (クライアント側で整形する場合)
+func newPriceFormatter(ctx *gin.Context) *priceFormatter {
+	if ctx.Query("formatPrices") == "false" {
+		return nil
+	}
+	tags, _, _ := language.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+	tag, _, _ := priceLanguages.Match(tags...)
+	printer := message.NewPrinter(tag)
+	return &priceFormatter{printer: printer, symbol: printer.Sprint(currency.NarrowSymbol(currency.JPY))}
+}
+
+func (f *priceFormatter) format(price uint) string {
+	return f.symbol + f.printer.Sprintf("%d", price)
+}
+
+// レスポンスに整形済みの価格を追加する
+func (f *priceFormatter) apply(response *dto.ItemResponse) {
+	if f == nil {
+		return
+	}
+	formatted := f.format(response.Price)
+	response.FormattedPrice = &formatted
+	if response.DiscountedPrice != nil {
+		formattedDiscounted := f.format(*response.DiscountedPrice)
+		response.FormattedDiscountedPrice = &formattedDiscounted
+	}
+}
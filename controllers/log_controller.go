@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ILogController interface {
+	FindLevels(ctx *gin.Context)
+	UpdateLevel(ctx *gin.Context)
+}
+
+type LogController struct {
+	audit *slog.Logger
+}
+
+func NewLogController() ILogController {
+	return &LogController{audit: infra.Logger("audit")}
+}
+
+func (c *LogController) FindLevels(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"data": infra.LogLevels()})
+}
+
+func (c *LogController) UpdateLevel(ctx *gin.Context) {
+	var input dto.UpdateLogLevelInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.JSON(http.StatusBadRequest, bindingErrorBody(err))
+		return
+	}
+	level, err := infra.ParseLogLevel(input.Level)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid level"})
+		return
+	}
+	var duration time.Duration
+	if input.Duration != "" {
+		duration, err = time.ParseDuration(input.Duration)
+		if err != nil || duration <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+			return
+		}
+	}
+
+	module := input.Module
+	previous, hadPrevious := infra.SetLogLevel(module, level, duration, func() {
+		c.audit.Info("log level reverted", "target", module)
+	})
+
+	from := "default"
+	if hadPrevious {
+		from = previous.String()
+	}
+	c.audit.Info("log level changed",
+		"target", input.Module,
+		"from", from,
+		"to", level.String(),
+		"duration", input.Duration,
+		"client_ip", ctx.ClientIP(),
+	)
+	ctx.JSON(http.StatusOK, gin.H{"data": infra.LogLevels()})
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IMarketController interface {
+	Sold(ctx *gin.Context)
+}
+
+// 調査や価格の参考のための、売れた商品の集計
+type MarketController struct {
+	service services.IItemService
+}
+
+func NewMarketController(service services.IItemService) IMarketController {
+	return &MarketController{service: service}
+}
+
+func (c *MarketController) Sold(ctx *gin.Context) {
+	var input dto.SoldStatsInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := c.service.SoldStats(input)
+	if err != nil {
+		if err.Error() == "Invalid category" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"data": stats})
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"fmt"
+	"gin-fleamarket/dto"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ページ分けした一覧にRFC 5988のLinkヘッダー (first / prev / next / last) を付ける
+// URLはリクエストのクエリのpageとlimitだけを書き換えた相対参照
+func setPaginationLinks(ctx *gin.Context, meta *dto.PaginationMeta) {
+	last := max(meta.TotalPages, 1)
+	links := []string{paginationLink(ctx, 1, meta.Limit, "first")}
+	if meta.Page > 1 {
+		links = append(links, paginationLink(ctx, min(meta.Page-1, last), meta.Limit, "prev"))
+	}
+	if meta.Page < last {
+		links = append(links, paginationLink(ctx, meta.Page+1, meta.Limit, "next"))
+	}
+	links = append(links, paginationLink(ctx, last, meta.Limit, "last"))
+	ctx.Header("Link", strings.Join(links, ", "))
+}
+
+func paginationLink(ctx *gin.Context, page int, limit int, rel string) string {
+	query := ctx.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, ctx.Request.URL.Path, query.Encode(), rel)
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IQRCodeController interface {
+	Item(ctx *gin.Context)
+}
+
+type QRCodeController struct {
+	itemService      services.IItemService
+	shortLinkService services.IShortLinkService
+	encoder          infra.IQRCodeEncoder
+	idCodec          infra.IIDCodec
+	baseURL          string
+}
+
+func NewQRCodeController(itemService services.IItemService, shortLinkService services.IShortLinkService, encoder infra.IQRCodeEncoder, idCodec infra.IIDCodec, baseURL string) IQRCodeController {
+	return &QRCodeController{
+		itemService:      itemService,
+		shortLinkService: shortLinkService,
+		encoder:          encoder,
+		idCodec:          idCodec,
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// 商品ページ (または短縮URL) のQRコードをPNGで返す
+// GETで副作用を起こさないよう短縮URLは作成せず、POST /items/:id/shortlink で作成済みのものだけを使う
+func (c *QRCodeController) Item(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input dto.QRCodeInput
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	url := c.baseURL + "/items/" + c.idCodec.Encode(itemId)
+	if input.Short {
+		shortLink, err := c.shortLinkService.FindByItemId(itemId)
+		if err != nil {
+			c.respondError(ctx, err)
+			return
+		}
+		url = c.baseURL + "/s/" + shortLink.Code
+	} else if _, err := c.itemService.FindById(itemId); err != nil {
+		c.respondError(ctx, err)
+		return
+	}
+
+	// 内容とサイズが同じなら画像も変わらないため、長めにキャッシュさせる
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", url, input.Size)))
+	etag := fmt.Sprintf("\"%x\"", sum[:8])
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	png, err := c.encoder.PNG(url, input.Size)
+	if err != nil {
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.Data(http.StatusOK, "image/png", png)
+}
+
+func (c *QRCodeController) respondError(ctx *gin.Context, err error) {
+	if err.Error() == "Item not found" || err.Error() == "Short link not found" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Error(err)
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+}
@@ -0,0 +1,42 @@
+package controllers_test
+
+import (
+	"gin-fleamarket/models"
+	"net/http"
+	"testing"
+)
+
+func TestQRCodeItem(t *testing.T) {
+	s := newTestServer(t)
+	seedCatalog(t, s)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "item url", method: http.MethodGet, path: "/items/1/qr.png", status: http.StatusOK},
+		{name: "unknown item", method: http.MethodGet, path: "/items/999/qr.png", status: http.StatusNotFound},
+		// 短縮URLはGETでは作成しない
+		{name: "short url before creation", method: http.MethodGet, path: "/items/1/qr.png?short=true", status: http.StatusNotFound},
+		{name: "create short url", method: http.MethodPost, path: "/items/1/shortlink", status: http.StatusCreated},
+		{name: "short url after creation", method: http.MethodGet, path: "/items/1/qr.png?short=true", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, nil)
+			if w.Code != tt.status {
+				t.Fatalf("%s %s status = %d, want %d\n%s", tt.method, tt.path, w.Code, tt.status, w.Body)
+			}
+		})
+	}
+
+	var count int64
+	if err := s.db.Model(&models.ShortLink{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count short links: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("short links = %d, want 1", count)
+	}
+}
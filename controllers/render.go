@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"encoding/json"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/pb"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Acceptヘッダーに応じてJSON / XML / MessagePackでレスポンスを返す
+func respond(ctx *gin.Context, code int, obj interface{}) {
+	// 商品のレスポンスはAccept-Languageで表示用の価格が変わる
+	ctx.Header("Vary", "Accept, Accept-Language")
+	switch ctx.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2, binding.MIMEMSGPACK, binding.MIMEMSGPACK2) {
+	case binding.MIMEXML, binding.MIMEXML2:
+		ctx.XML(code, obj)
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		ctx.Render(code, render.MsgPack{Data: obj})
+	default:
+		ctx.JSON(code, obj)
+	}
+}
+
+// 商品一覧は内部向けにapplication/x-protobufでも返せるようにする
+// 公開のエンドポイントでも返すため、IDはJSONと同じく変換する (ページの情報はLinkヘッダーだけで返す)
+// ページ分けしていない場合、metaはnil
+// 商品はeachでバッチごとに受け取る (JSON以外は全件を集めてから返す)
+func (c *ItemController) respondItems(ctx *gin.Context, code int, each func(fn func(items []models.Item) error) error, meta *dto.PaginationMeta) {
+	format := ctx.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2, binding.MIMEMSGPACK, binding.MIMEMSGPACK2, binding.MIMEPROTOBUF)
+	if format == binding.MIMEJSON {
+		c.streamItems(ctx, code, each, meta)
+		return
+	}
+	items := []models.Item{}
+	if err := each(func(batch []models.Item) error {
+		items = append(items, batch...)
+		return nil
+	}); err != nil {
+		ctx.Error(err)
+		respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	if format == binding.MIMEPROTOBUF {
+		ctx.Header("Vary", "Accept")
+		ctx.ProtoBuf(code, c.toProtoItemList(&items))
+		return
+	}
+	responses := make([]dto.ItemResponse, 0, len(items))
+	for _, v := range items {
+		responses = append(responses, c.toResponse(ctx, &v))
+	}
+	if meta != nil {
+		respond(ctx, code, gin.H{"data": responses, "meta": meta})
+		return
+	}
+	respond(ctx, code, gin.H{"data": responses})
+}
+
+// 読み込み済みの商品をrespondItemsに渡す
+func itemsOf(items *[]models.Item) func(fn func(items []models.Item) error) error {
+	return func(fn func(items []models.Item) error) error {
+		return fn(*items)
+	}
+}
+
+// JSONでは {"data":[...],"meta":{...}} を要素ごとに書き出し、レスポンス全体をメモリに組み立てない
+// 要素ごとに1回のWriteにするため、FieldNamingの変換も要素ごとに行われる
+func (c *ItemController) streamItems(ctx *gin.Context, code int, each func(fn func(items []models.Item) error) error, meta *dto.PaginationMeta) {
+	writer := &itemsWriter{ctx: ctx, code: code, encoder: json.NewEncoder(ctx.Writer)}
+	err := each(func(items []models.Item) error {
+		for i := range items {
+			if err := writer.write(c.toResponse(ctx, &items[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.Error(err)
+		if !writer.started {
+			respond(ctx, http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		}
+		// 書き出し始めた後はヘッダーを送信済みのため、ステータスコードは変更できない
+		return
+	}
+	if err := writer.close(meta); err != nil {
+		ctx.Error(err)
+	}
+}
+
+// 一覧のJSONを要素ごとに書き出す
+// 最初の要素を書くまでヘッダーを送らないため、読み込みの最初で失敗した場合はエラーを返せる
+type itemsWriter struct {
+	ctx     *gin.Context
+	code    int
+	encoder *json.Encoder
+	started bool
+	count   int
+}
+
+func (w *itemsWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	w.ctx.Header("Vary", "Accept, Accept-Language")
+	w.ctx.Header("Content-Type", "application/json; charset=utf-8")
+	w.ctx.Status(w.code)
+	_, err := w.ctx.Writer.Write([]byte(`{"data":[`))
+	return err
+}
+
+func (w *itemsWriter) write(item dto.ItemResponse) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.count > 0 {
+		if _, err := w.ctx.Writer.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	w.count++
+	return w.encoder.Encode(item)
+}
+
+func (w *itemsWriter) close(meta *dto.PaginationMeta) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if meta == nil {
+		_, err := w.ctx.Writer.Write([]byte("]}"))
+		return err
+	}
+	if _, err := w.ctx.Writer.Write([]byte(`],"meta":`)); err != nil {
+		return err
+	}
+	if err := w.encoder.Encode(meta); err != nil {
+		return err
+	}
+	_, err := w.ctx.Writer.Write([]byte("}"))
+	return err
+}
+
+func (c *ItemController) toProtoItemList(items *[]models.Item) *pb.ItemList {
+	list := &pb.ItemList{Items: make([]*pb.Item, 0, len(*items))}
+	for _, v := range *items {
+		item := &pb.Item{
+			Id:          c.idCodec.Encode(v.ID),
+			Name:        v.Name,
+			Price:       uint64(v.Price),
+			Description: v.Description,
+			SoldOut:     v.SoldOut,
+			CreatedAt:   timestamppb.New(v.CreatedAt),
+			UpdatedAt:   timestamppb.New(v.UpdatedAt),
+		}
+		if v.CategoryID != nil {
+			categoryId := uint64(*v.CategoryID)
+			item.CategoryId = &categoryId
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/services"
+	"gin-fleamarket/testutil/factory"
+	"gin-fleamarket/testutil/testdb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 書き出しの件数 (全件のエクスポートを想定する)
+const benchmarkItems = 100_000
+
+// ボディを捨てるResponseWriter (レスポンスの保持に使うメモリを計測に含めない)
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header            { return w.header }
+func (w *discardResponseWriter) Write(data []byte) (int, error) { return len(data), nil }
+func (w *discardResponseWriter) WriteHeader(code int)           {}
+
+// 一覧を全件読み込んでから返す場合と、バッチごとに書き出す場合のメモリの使用量を比べる
+func BenchmarkFindAllJSON(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(b)
+	items := make([]models.Item, 0, benchmarkItems)
+	for range benchmarkItems {
+		items = append(items, factory.NewItemFactory(db).Build())
+	}
+	if err := db.CreateInBatches(items, 1000).Error; err != nil {
+		b.Fatalf("failed to create items: %v", err)
+	}
+
+	clock := infra.NewFakeClock(time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC))
+	categoryService := services.NewCategoryService(repositories.NewCategoryRepository(db))
+	campaignService := services.NewCampaignService(repositories.NewCampaignRepository(db), categoryService, clock)
+	itemService := services.NewItemService(repositories.NewItemRepository(db), categoryService, campaignService, infra.NewVisionProvider(), clock)
+	c := NewItemController(itemService, infra.NewIDCodec("")).(*ItemController)
+
+	router := gin.New()
+	// 変更前の実装: 全件をスライスに集め、レスポンス全体を1度にエンコードする
+	router.GET("/slice", func(ctx *gin.Context) {
+		list, err := itemService.FindAll(dto.FindItemsInput{})
+		if err != nil {
+			b.Fatalf("FindAll failed: %v", err)
+		}
+		items := []models.Item{}
+		if err := list.Each(func(batch []models.Item) error {
+			items = append(items, batch...)
+			return nil
+		}); err != nil {
+			b.Fatalf("Each failed: %v", err)
+		}
+		responses := make([]dto.ItemResponse, 0, len(items))
+		for _, v := range items {
+			responses = append(responses, c.toResponse(ctx, &v))
+		}
+		ctx.JSON(http.StatusOK, gin.H{"data": responses})
+	})
+	router.GET("/stream", c.FindAll)
+
+	for _, path := range []string{"/slice", "/stream"} {
+		b.Run(path[1:], func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				w := &discardResponseWriter{header: http.Header{}}
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			}
+		})
+	}
+}
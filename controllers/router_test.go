@@ -0,0 +1,81 @@
+package controllers_test
+
+import (
+	"gin-fleamarket/controllers"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/middlewares"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/services"
+	"gin-fleamarket/testutil/testdb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// テストの現在時刻 (キャンペーンの期間などの基準)
+var testNow = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+const testBaseURL = "https://example.com"
+
+// 実行ごとに変わる値 (ゴールデンファイルでは置き換える)
+var volatileKeys = []string{"createdAt", "updatedAt", "requestId", "traceId"}
+
+// main.goと同じ順にミドルウェアをかけた、DB以外は本物のルーター
+type testServer struct {
+	router *gin.Engine
+	db     *gorm.DB
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithIDCodec(t, infra.NewIDCodec(""))
+}
+
+// 公開用のIDの変換を指定する (既定はIDをそのまま返す)
+func newTestServerWithIDCodec(t *testing.T, idCodec infra.IIDCodec) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t)
+	clock := infra.NewFakeClock(testNow)
+
+	categoryService := services.NewCategoryService(repositories.NewCategoryRepository(db))
+	campaignService := services.NewCampaignService(repositories.NewCampaignRepository(db), categoryService, clock)
+	itemService := services.NewItemService(repositories.NewItemRepository(db), categoryService, campaignService, infra.NewVisionProvider(), clock)
+	itemController := controllers.NewItemController(itemService, idCodec)
+	categoryController := controllers.NewCategoryController(categoryService)
+	campaignController := controllers.NewCampaignController(campaignService)
+	shortLinkService := services.NewShortLinkService(repositories.NewShortLinkRepository(db), itemService, infra.NewTokenGenerator())
+	shortLinkController := controllers.NewShortLinkController(shortLinkService, idCodec, testBaseURL)
+	qrCodeController := controllers.NewQRCodeController(itemService, shortLinkService, infra.NewQRCodeEncoder(), idCodec, testBaseURL)
+
+	router := gin.New()
+	router.Use(middlewares.RequestID(infra.NewTokenGenerator()), middlewares.FieldNaming(middlewares.FieldNamingCamel))
+	router.GET("/items", itemController.FindAll)
+	router.GET("/items/search", itemController.Search)
+	router.GET("/items/:id", itemController.FindById)
+	router.POST("/items", itemController.Create)
+	router.POST("/items/search/by-image", itemController.SearchByImage)
+	router.PATCH("/items/:id", itemController.Patch)
+	router.DELETE("/items/:id", itemController.Delete)
+	router.HEAD("/items", itemController.FindAll)
+	router.HEAD("/items/:id", itemController.FindById)
+	router.GET("/categories", categoryController.FindAll)
+	router.GET("/campaigns/active", campaignController.FindActive)
+	router.POST("/items/:id/shortlink", shortLinkController.Create)
+	router.GET("/items/:id/qr.png", qrCodeController.Item)
+	return &testServer{router: router, db: db}
+}
+
+func (s *testServer) do(method string, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IShortLinkController interface {
+	Create(ctx *gin.Context)
+	Redirect(ctx *gin.Context)
+	Stats(ctx *gin.Context)
+}
+
+type ShortLinkController struct {
+	service services.IShortLinkService
+	idCodec infra.IIDCodec
+	baseURL string
+}
+
+func NewShortLinkController(service services.IShortLinkService, idCodec infra.IIDCodec, baseURL string) IShortLinkController {
+	return &ShortLinkController{service: service, idCodec: idCodec, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (c *ShortLinkController) Create(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	shortLink, created, err := c.service.FindOrCreate(itemId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	ctx.JSON(status, gin.H{"data": gin.H{
+		"code": shortLink.Code,
+		"url":  c.baseURL + "/s/" + shortLink.Code,
+	}})
+}
+
+func (c *ShortLinkController) Redirect(ctx *gin.Context) {
+	shortLink, err := c.service.Resolve(ctx.Param("code"), ctx.Request.Referer())
+	if err != nil {
+		if err.Error() == "Short link not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	// 共有された後に転送先が変わることはないが、クリック数を数えるためキャッシュさせない
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, c.baseURL+"/items/"+c.idCodec.Encode(shortLink.ItemID))
+}
+
+func (c *ShortLinkController) Stats(ctx *gin.Context) {
+	itemId, err := c.idCodec.Decode(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	stats, err := c.service.Stats(itemId)
+	if err != nil {
+		if err.Error() == "Item not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Error(err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected Error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": stats})
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"gin-fleamarket/models"
+	"time"
+)
+
+type CreateCampaignInput struct {
+	Name            string    `json:"name" binding:"required"`
+	StartsAt        time.Time `json:"startsAt" binding:"required"`
+	EndsAt          time.Time `json:"endsAt" binding:"required,gtfield=StartsAt"`
+	CategoryID      *uint     `json:"categoryId"`
+	DiscountPercent uint      `json:"discountPercent" binding:"max=100"`
+	DiscountAmount  uint      `json:"discountAmount"`
+}
+
+type CampaignResponse struct {
+	ID              uint      `json:"id"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	Name            string    `json:"name"`
+	StartsAt        time.Time `json:"startsAt"`
+	EndsAt          time.Time `json:"endsAt"`
+	CategoryID      *uint     `json:"categoryId"`
+	DiscountPercent uint      `json:"discountPercent"`
+	DiscountAmount  uint      `json:"discountAmount"`
+}
+
+func NewCampaignResponse(campaign models.Campaign) CampaignResponse {
+	return CampaignResponse{
+		ID:              campaign.ID,
+		CreatedAt:       campaign.CreatedAt,
+		UpdatedAt:       campaign.UpdatedAt,
+		Name:            campaign.Name,
+		StartsAt:        campaign.StartsAt,
+		EndsAt:          campaign.EndsAt,
+		CategoryID:      campaign.CategoryID,
+		DiscountPercent: campaign.DiscountPercent,
+		DiscountAmount:  campaign.DiscountAmount,
+	}
+}
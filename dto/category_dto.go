@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"gin-fleamarket/models"
+	"time"
+)
+
+type CreateCategoryInput struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parentId"`
+	Position int    `json:"position"`
+}
+
+type MoveCategoryInput struct {
+	// nilの場合はルートに移動する
+	ParentID *uint `json:"parentId"`
+	Position *int  `json:"position"`
+}
+
+type MergeCategoryInput struct {
+	// 統合先のカテゴリ
+	TargetID uint `json:"targetId" binding:"required"`
+}
+
+type UpdateTranslationsInput struct {
+	// 言語ごとの名前 (含めなかった言語の翻訳は削除する)
+	Translations map[string]string `json:"translations" binding:"dive,required"`
+}
+
+type AttributeDefinitionInput struct {
+	Key      string   `json:"key" binding:"required"`
+	Type     string   `json:"type" binding:"required,oneof=string number boolean enum"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" binding:"required_if=Type enum"`
+}
+
+type UpdateAttributeSchemaInput struct {
+	Attributes []AttributeDefinitionInput `json:"attributes" binding:"dive"`
+}
+
+type CategoryResponse struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Accept-Languageに合わせた名前 (翻訳がない場合は日本語)
+	Name            string                 `json:"name"`
+	Translations    models.LocalizedNames  `json:"translations"`
+	ParentID        *uint                  `json:"parentId"`
+	Path            string                 `json:"path"`
+	Position        int                    `json:"position"`
+	AttributeSchema models.AttributeSchema `json:"attributeSchema"`
+	// 一覧の場合のみ
+	ItemCount *int64 `json:"itemCount,omitempty"`
+}
+
+// 優先順で最初に翻訳がある言語の名前を使う (どれもなければ日本語の名前)
+func NewCategoryResponse(category models.Category, languages []string) CategoryResponse {
+	name := category.Name
+	for _, language := range languages {
+		if language == "ja" {
+			break
+		}
+		if translated, ok := category.Translations[language]; ok {
+			name = translated
+			break
+		}
+	}
+	return CategoryResponse{
+		ID:              category.ID,
+		CreatedAt:       category.CreatedAt,
+		UpdatedAt:       category.UpdatedAt,
+		Name:            name,
+		Translations:    category.Translations,
+		ParentID:        category.ParentID,
+		Path:            category.Path,
+		Position:        category.Position,
+		AttributeSchema: category.AttributeSchema,
+		ItemCount:       category.ItemCount,
+	}
+}
+
+func NewCategoryResponses(categories []models.Category, languages []string) []CategoryResponse {
+	responses := make([]CategoryResponse, 0, len(categories))
+	for _, v := range categories {
+		responses = append(responses, NewCategoryResponse(v, languages))
+	}
+	return responses
+}
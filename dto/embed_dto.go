@@ -0,0 +1,22 @@
+package dto
+
+// oEmbedのmaxwidth / maxheight (0は指定なし)
+type EmbedInput struct {
+	MaxWidth  int `form:"maxwidth" binding:"min=0"`
+	MaxHeight int `form:"maxheight" binding:"min=0"`
+}
+
+// oEmbed 1.0 の "rich" タイプのレスポンス
+type EmbedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	// oEmbedの拡張 (埋め込み側で独自に表示する場合に使う)
+	Price uint   `json:"price"`
+	URL   string `json:"url"`
+}
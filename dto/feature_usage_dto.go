@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+type FeatureUsageInput struct {
+	Period string `form:"period,default=7d" binding:"oneof=24h 7d 30d 90d"`
+	// 集計の区切り (日ごとの区切りはサーバーのタイムゾーンの0時)
+	Bucket string `form:"bucket,default=day" binding:"oneof=hour day"`
+}
+
+// 集計の区切り
+const (
+	UsageBucketHour = "hour"
+	UsageBucketDay  = "day"
+)
+
+// 期間内の利用回数 (まだ書き込んでいない直近の分は含まない)
+type FeatureUsageReport struct {
+	Period    string        `json:"period"`
+	Bucket    string        `json:"bucket"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Endpoints []UsageSeries `json:"endpoints"`
+	Features  []UsageSeries `json:"features"`
+}
+
+// 利用回数の多い順に並べる
+type UsageSeries struct {
+	Name  string `json:"name"`
+	Total int64  `json:"total"`
+	// 利用のあった区切りだけを古い順に返す
+	Buckets []UsageBucket `json:"buckets"`
+}
+
+type UsageBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type FeedInput struct {
+	CategoryID *uint `form:"categoryId"`
+}
@@ -1,14 +1,192 @@
 package dto
 
+import (
+	"gin-fleamarket/models"
+	"time"
+)
+
 type CreateItemInput struct {
-	Name        string `json:"name" binding:"required,min=2"`
-	Price       uint   `json:"price" binding:"required,min=1,max=999999"`
-	Description string `json:"description"`
+	Name        string                 `json:"name" binding:"required,min=2"`
+	Price       uint                   `json:"price" binding:"required,min=1,max=999999"`
+	Description string                 `json:"description"`
+	CategoryID  *uint                  `json:"categoryId"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	Metadata    map[string]interface{} `json:"metadata"`
 }
 
 type UpdateItemInput struct {
-	Name        *string `json:"name" binding:"omitempty,min=2"`
-	Price       *uint   `json:"price" binding:"omitempty,min=1,max=999999"`
-	Description *string `json:"description"`
-	SoldOut     *bool   `json:"soldOut"`
+	Name        *string                 `json:"name" binding:"omitempty,min=2"`
+	Price       *uint                   `json:"price" binding:"omitempty,min=1,max=999999"`
+	Description *string                 `json:"description"`
+	SoldOut     *bool                   `json:"soldOut"`
+	CategoryID  *uint                   `json:"categoryId"`
+	Attributes  *map[string]interface{} `json:"attributes"`
+	// PUTでは置き換え、PATCHではキーごとにマージする (nullのキーは削除)
+	Metadata *map[string]interface{} `json:"metadata"`
+	// 空文字にすると商品名を使う
+	ImageAltText *string `json:"imageAltText" binding:"omitempty,max=250"`
+}
+
+type UpdateLegalHoldInput struct {
+	LegalHold *bool `json:"legalHold" binding:"required"`
+}
+
+// 保全の状態は管理者にだけ返す
+type LegalHoldResponse struct {
+	ID        string `json:"id"`
+	LegalHold bool   `json:"legalHold"`
+}
+
+type FindItemsInput struct {
+	// 指定したカテゴリとその子孫カテゴリの商品に絞り込む
+	CategoryID *uint `form:"categoryId"`
+	// attributes[size]=M のように属性の値で絞り込む
+	Attributes map[string]string `form:"-"`
+	// metadata[brand]=xxx のようにメタデータの値で絞り込む
+	Metadata map[string]string `form:"-"`
+	// どちらかを指定した場合はページ分けする (省略した場合は全件)
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+	// 並び順 (省略した場合は新しい順)
+	Sort string `form:"sort" binding:"omitempty,oneof=newest recommended"`
+}
+
+// 商品一覧の並び順
+const (
+	SortNewest      = "newest"
+	SortRecommended = "recommended"
+)
+
+type SearchItemsInput struct {
+	// 空白で区切った語を全て含む商品を探す
+	Q          string `form:"q" binding:"required"`
+	CategoryID *uint  `form:"categoryId"`
+	// 一致した語を<mark>で囲んだ抜粋を含める
+	Highlight bool `form:"highlight"`
+}
+
+// 検索結果の絞り込み項目ごとの件数
+type SearchFacets struct {
+	Categories []CategoryFacet  `json:"categories"`
+	Prices     []PriceFacet     `json:"prices"`
+	Conditions []ConditionFacet `json:"conditions"`
+}
+
+type CategoryFacet struct {
+	CategoryID uint  `json:"categoryId"`
+	Count      int64 `json:"count"`
+}
+
+// Min円以上Max円未満の件数 (Maxがnilの場合は上限なし)
+type PriceFacet struct {
+	Min   uint  `json:"min"`
+	Max   *uint `json:"max"`
+	Count int64 `json:"count"`
+}
+
+type ConditionFacet struct {
+	Condition string `json:"condition"`
+	Count     int64  `json:"count"`
+}
+
+type SuggestItemsInput struct {
+	Q string `form:"q" binding:"required,max=50"`
+}
+
+// 入力補完の候補
+type SuggestResponse struct {
+	// 入力中の語を補完した検索語
+	Completions []string `json:"completions"`
+	// 名前が一致する商品
+	Items []ItemSuggestion `json:"items"`
+}
+
+type ItemSuggestion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PriceSuggestionInput struct {
+	Name       string `form:"name" binding:"required,max=100"`
+	CategoryID *uint  `form:"category"`
+}
+
+// 最近売れた似た商品の価格から求めた提案の価格帯
+// 比較できる商品が少ない場合、価格はnil
+type PriceSuggestion struct {
+	// 集計した商品の件数
+	Comparables int64 `json:"comparables"`
+	// 25パーセンタイル / 中央値 / 75パーセンタイル
+	Low    *uint `json:"low"`
+	Median *uint `json:"median"`
+	High   *uint `json:"high"`
+	// この日時以降に売れた商品で集計した
+	Since time.Time `json:"since"`
+}
+
+type SoldStatsInput struct {
+	CategoryID *uint  `form:"category"`
+	Period     string `form:"period,default=30d" binding:"oneof=7d 30d 90d 365d"`
+}
+
+// 売れた商品の価格の傾向
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// 期間内に売れた商品の集計 (個々の商品や出品者の情報は含めない)
+type SoldStats struct {
+	Period string    `json:"period"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Count  int64     `json:"count"`
+	// 件数が少ない場合はnil
+	MedianPrice *uint `json:"medianPrice"`
+	// 直前の同じ長さの期間からの変化率 (0.1は10%増、比べられない場合はnil)
+	CountChange       *float64 `json:"countChange"`
+	MedianPriceChange *float64 `json:"medianPriceChange"`
+	// 中央値の変化の向き (TrendUp / TrendDown / TrendFlat、比べられない場合はnil)
+	Trend *string `json:"trend"`
+}
+
+type StreamItemsInput struct {
+	// 指定した日時以降に更新された商品だけを返す (RFC3339)
+	Since *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// 商品のレスポンス (IDは公開用の表記に変換済み)
+type ItemResponse struct {
+	ID          string     `json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt"`
+	Name        string     `json:"name"`
+	Price       uint       `json:"price"`
+	Description string     `json:"description"`
+	SoldOut     bool       `json:"soldOut"`
+	ImageHash   string     `json:"imageHash"`
+	// 画像がない場合は空
+	ImageAltText  string             `json:"imageAltText"`
+	CategoryID    *uint              `json:"categoryId"`
+	Attributes    models.JSONMap     `json:"attributes"`
+	Metadata      models.JSONMap     `json:"metadata"`
+	ClosureReason string             `json:"closureReason"`
+	ClosedAt      *time.Time         `json:"closedAt"`
+	Breadcrumb    []CategoryResponse `json:"breadcrumb"`
+	// キャンペーン中の場合のみ
+	DiscountedPrice *uint `json:"discountedPrice"`
+	CampaignID      *uint `json:"campaignId"`
+	// Accept-Languageに合わせて整形した価格 (?formatPrices=false の場合は含めない)
+	FormattedPrice           *string `json:"formattedPrice"`
+	FormattedDiscountedPrice *string `json:"formattedDiscountedPrice"`
+	// 検索で ?highlight=true の場合のみ
+	Highlight *ItemHighlight `json:"highlight,omitempty"`
+}
+
+// 検索語を<mark>で囲んだ名前と説明文の抜粋 (HTMLとしてエスケープ済み)
+type ItemHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
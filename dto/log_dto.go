@@ -0,0 +1,9 @@
+package dto
+
+type UpdateLogLevelInput struct {
+	// 空の場合は全体のレベルを変更する
+	Module string `json:"module"`
+	Level  string `json:"level" binding:"required"`
+	// 指定した場合はその時間が経つと元のレベルに戻す (例: "15m")
+	Duration string `json:"duration"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// ページ分けした一覧のレスポンスに付ける情報 (ページ番号は1から数える)
+type PaginationMeta struct {
+	TotalItems int64 `json:"totalItems"`
+	TotalPages int   `json:"totalPages"`
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+}
+
+func NewPaginationMeta(totalItems int64, page int, limit int) PaginationMeta {
+	return PaginationMeta{
+		TotalItems: totalItems,
+		TotalPages: int((totalItems + int64(limit) - 1) / int64(limit)),
+		Page:       page,
+		Limit:      limit,
+	}
+}
@@ -0,0 +1,8 @@
+package dto
+
+type QRCodeInput struct {
+	// 画像の一辺のピクセル数
+	Size int `form:"size,default=256" binding:"min=64,max=1024"`
+	// trueの場合は短縮URLを埋め込む (作成されていない場合は404)
+	Short bool `form:"short"`
+}
@@ -0,0 +1,49 @@
+package infra
+
+import (
+	"errors"
+	"math/rand/v2"
+
+	"gorm.io/gorm"
+)
+
+var ErrChaosInjected = errors.New("chaos: injected transient database failure")
+
+// 一定の割合でDB操作を失敗させるGORMプラグイン
+// リトライやサーキットブレーカーの検証に使う
+type ChaosPlugin struct {
+	errorRate float64
+}
+
+func NewChaosPlugin(errorRate float64) *ChaosPlugin {
+	return &ChaosPlugin{errorRate: errorRate}
+}
+
+// Name implements gorm.Plugin.
+func (p *ChaosPlugin) Name() string {
+	return "chaos"
+}
+
+// Initialize implements gorm.Plugin.
+func (p *ChaosPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("chaos:create", p.inject); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("chaos:query", p.inject); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("chaos:update", p.inject); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("chaos:delete", p.inject); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("chaos:row", p.inject)
+}
+
+func (p *ChaosPlugin) inject(db *gorm.DB) {
+	if rand.Float64() < p.errorRate {
+		db.AddError(ErrChaosInjected)
+	}
+}
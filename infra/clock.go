@@ -0,0 +1,52 @@
+package infra
+
+import (
+	"sync"
+	"time"
+)
+
+// 現在時刻の取得を差し替えられるようにする
+// 有効期限など時刻に依存する処理を、sleepせずに確認できるようにするため
+type IClock interface {
+	Now() time.Time
+}
+
+func NewSystemClock() IClock {
+	return &SystemClock{}
+}
+
+type SystemClock struct{}
+
+// Now implements IClock.
+func (c *SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// 任意の時刻に固定し、Advanceで進められる時計
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements IClock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,203 @@
+package infra
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 環境変数から読み込むアプリケーションの設定
+type Config struct {
+	Chaos    ChaosConfig
+	LoadShed LoadShedConfig
+	// 同じ内容の作成リクエストを重複とみなす時間 (0で無効)
+	DedupeWindow time.Duration
+	Cache        CacheConfig
+	// 公開用IDの変換に使うsalt (空の場合は数値のまま)
+	IDSalt    string
+	Log       LogConfig
+	AccessLog AccessLogConfig
+	// フィードなどで使うサービスの公開URL
+	PublicBaseURL string
+	// 管理者APIの認証に使うトークン (空の場合は管理者APIを使えない)
+	AdminToken string
+	// レスポンスのJSONのフィールド名の既定の規則 ("camel" または移行期間中の "legacy")
+	FieldNaming string
+	// 終了時に処理中のリクエストや後片付けを待つ時間 (超えた分は打ち切る)
+	ShutdownTimeout time.Duration
+	// 売れた商品の集計API (/market) の回数制限
+	MarketRateLimit RateLimitConfig
+	// 数えておいた機能の利用回数をDBに書き込む間隔
+	FeatureUsageFlushInterval time.Duration
+}
+
+// 送信元ごとのリクエスト数の制限
+type RateLimitConfig struct {
+	// Windowの間に許可する回数 (0で制限しない)
+	Limit  int64
+	Window time.Duration
+}
+
+// 種類ごとのプロセス内キャッシュの期間
+type CacheConfig struct {
+	Categories CachePolicy
+	ItemCounts CachePolicy
+}
+
+type CachePolicy struct {
+	// この期間はキャッシュした値をそのまま返す (0で無効)
+	TTL time.Duration
+	// TTLを過ぎてからこの期間は古い値を返しつつ、裏で読み込み直す
+	StaleWhileRevalidate time.Duration
+}
+
+// アクセスログのサンプリングの設定
+type AccessLogConfig struct {
+	// 成功したリクエストを記録する割合 (0.0〜1.0)
+	SampleRate float64
+	// "GET /items=0.1,GET /ping=0" の形式でルートごとの割合を指定する
+	RouteSampleRates map[string]float64
+	// combined形式のアクセスログの出力先ファイル (空の場合は出力しない、"-"は標準出力)
+	CombinedPath string
+}
+
+// ログレベルの初期値 (実行中は PUT /admin/log-level で変更できる)
+// 設定を読み直した場合は、PUT /admin/log-level での変更も設定の値に戻る
+type LogConfig struct {
+	Level string
+	// "repositories=debug,services=warn" の形式でモジュールごとのレベルを指定する
+	Modules string
+}
+
+// 障害注入の設定 (開発・検証環境専用)
+type ChaosConfig struct {
+	Enabled bool
+	// リクエストに遅延を入れる割合 (0.0〜1.0) と遅延時間
+	LatencyRate float64
+	Latency     time.Duration
+	// リクエストをエラーにする割合 (0.0〜1.0)
+	ErrorRate float64
+	// DBの操作を一時的な障害として失敗させる割合 (0.0〜1.0)
+	DBErrorRate float64
+}
+
+// 過負荷時に優先度の低いリクエストを断るための設定
+type LoadShedConfig struct {
+	Enabled bool
+	// 処理中のリクエスト数の上限
+	MaxInFlight int64
+	// コネクションプールの平均待ち時間の上限
+	MaxDBWait time.Duration
+	// プールの統計を確認する間隔
+	Interval time.Duration
+}
+
+func LoadConfig() *Config {
+	return &Config{
+		Chaos: ChaosConfig{
+			Enabled:     getEnvBool("CHAOS_ENABLED", false),
+			LatencyRate: getEnvFloat("CHAOS_LATENCY_RATE", 0),
+			Latency:     getEnvDuration("CHAOS_LATENCY", 500*time.Millisecond),
+			ErrorRate:   getEnvFloat("CHAOS_ERROR_RATE", 0),
+			DBErrorRate: getEnvFloat("CHAOS_DB_ERROR_RATE", 0),
+		},
+		LoadShed: LoadShedConfig{
+			Enabled:     getEnvBool("LOAD_SHED_ENABLED", true),
+			MaxInFlight: getEnvInt("LOAD_SHED_MAX_IN_FLIGHT", 200),
+			MaxDBWait:   getEnvDuration("LOAD_SHED_MAX_DB_WAIT", 100*time.Millisecond),
+			Interval:    getEnvDuration("LOAD_SHED_INTERVAL", time.Second),
+		},
+		DedupeWindow: getEnvDuration("DEDUPE_WINDOW", 5*time.Second),
+		Cache: CacheConfig{
+			Categories: CachePolicy{
+				TTL:                  getEnvDuration("CATEGORY_CACHE_TTL", time.Minute),
+				StaleWhileRevalidate: getEnvDuration("CATEGORY_CACHE_STALE", 10*time.Minute),
+			},
+			ItemCounts: CachePolicy{
+				TTL:                  getEnvDuration("CATEGORY_COUNT_CACHE_TTL", 30*time.Second),
+				StaleWhileRevalidate: getEnvDuration("CATEGORY_COUNT_CACHE_STALE", 5*time.Minute),
+			},
+		},
+		IDSalt: getEnv("ID_SALT", ""),
+		Log: LogConfig{
+			Level:   getEnv("LOG_LEVEL", "info"),
+			Modules: getEnv("LOG_LEVELS", ""),
+		},
+		AccessLog: AccessLogConfig{
+			SampleRate:       getEnvFloat("ACCESS_LOG_SAMPLE_RATE", 1),
+			RouteSampleRates: getEnvRates("ACCESS_LOG_ROUTE_SAMPLE_RATES"),
+			CombinedPath:     getEnv("ACCESS_LOG_COMBINED_PATH", ""),
+		},
+		PublicBaseURL:   getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		AdminToken:      getEnv("ADMIN_TOKEN", ""),
+		FieldNaming:     getEnv("JSON_FIELD_NAMING", "camel"),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		MarketRateLimit: RateLimitConfig{
+			Limit:  getEnvInt("MARKET_RATE_LIMIT", 30),
+			Window: getEnvDuration("MARKET_RATE_WINDOW", time.Minute),
+		},
+		FeatureUsageFlushInterval: getEnvDuration("FEATURE_USAGE_FLUSH_INTERVAL", time.Minute),
+	}
+}
+
+func getEnv(key string, defaultValue string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		recordSetting(key, v, false)
+		return v
+	}
+	recordSetting(key, defaultValue, true)
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	v, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		panic(key + " must be a boolean: " + err.Error())
+	}
+	return v
+}
+
+func getEnvInt(key string, defaultValue int64) int64 {
+	v, err := strconv.ParseInt(getEnv(key, strconv.FormatInt(defaultValue, 10)), 10, 64)
+	if err != nil {
+		panic(key + " must be an integer: " + err.Error())
+	}
+	return v
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	v, err := strconv.ParseFloat(getEnv(key, strconv.FormatFloat(defaultValue, 'f', -1, 64)), 64)
+	if err != nil {
+		panic(key + " must be a number: " + err.Error())
+	}
+	return v
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	v, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil {
+		panic(key + " must be a duration: " + err.Error())
+	}
+	return v
+}
+
+// "key=0.5,key2=1" の形式を読み込む
+func getEnvRates(key string) map[string]float64 {
+	rates := map[string]float64{}
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			panic(key + " must be key=rate pairs: " + pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			panic(key + " must be key=rate pairs: " + err.Error())
+		}
+		rates[strings.TrimSpace(k)] = rate
+	}
+	return rates
+}
@@ -2,8 +2,8 @@ package infra
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -11,21 +11,28 @@ import (
 
 func SetupDB() *gorm.DB {
 	// DB_PORTを整数に変換
-	port, err := strconv.Atoi(os.Getenv("DB_PORT"))
+	port, err := strconv.Atoi(getEnv("DB_PORT", ""))
 	if err != nil {
 		panic("DB_PORT must be a valid integer: " + err.Error())
 	}
 
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Tokyo",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
+		getEnv("DB_HOST", ""),
+		getEnv("DB_USER", ""),
+		getEnv("DB_PASSWORD", ""),
+		getEnv("DB_NAME", ""),
 		port,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		// 制約違反をgorm.ErrDuplicatedKeyなどに変換して、リポジトリで判定できるようにする
+		TranslateError: true,
+		// PostgreSQLの精度(マイクロ秒)に揃えて、保存前後で日時(ETag)が変わらないようにする
+		NowFunc: func() time.Time {
+			return time.Now().Truncate(time.Microsecond)
+		},
+	})
 	if err != nil {
 		panic("failed to connect to database: ")
 	}
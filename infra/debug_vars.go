@@ -0,0 +1,32 @@
+package infra
+
+import (
+	"database/sql"
+	"expvar"
+	"runtime"
+)
+
+// 本番の監視用に、ゴルーチン数やDBコネクションプールの統計をexpvarで公開する
+// (expvarの既定のmemstatsとcmdlineに加えて返す)
+func PublishDebugVars(db *sql.DB, inFlight func() int64) {
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("db", expvar.Func(func() any {
+		stats := db.Stats()
+		return map[string]any{
+			"maxOpenConnections": stats.MaxOpenConnections,
+			"openConnections":    stats.OpenConnections,
+			"inUse":              stats.InUse,
+			"idle":               stats.Idle,
+			"waitCount":          stats.WaitCount,
+			"waitDurationMs":     stats.WaitDuration.Milliseconds(),
+			"maxIdleClosed":      stats.MaxIdleClosed,
+			"maxIdleTimeClosed":  stats.MaxIdleTimeClosed,
+			"maxLifetimeClosed":  stats.MaxLifetimeClosed,
+		}
+	}))
+	expvar.Publish("inFlightRequests", expvar.Func(func() any {
+		return inFlight()
+	}))
+}
@@ -0,0 +1,129 @@
+package infra
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// APIの境界で数値のIDを公開用の文字列に変換する
+// 連番のIDから出品数などを推測されないようにするため差し替えられるようにしておく
+type IIDCodec interface {
+	Encode(id uint) string
+	Decode(s string) (uint, error)
+}
+
+var ErrInvalidID = errors.New("invalid id")
+
+// saltが空の場合はIDをそのまま10進数で返す
+func NewIDCodec(salt string) IIDCodec {
+	if salt == "" {
+		return &DecimalIDCodec{}
+	}
+	return NewHashIDCodec(salt)
+}
+
+type DecimalIDCodec struct{}
+
+// Encode implements IIDCodec.
+func (c *DecimalIDCodec) Encode(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// Decode implements IIDCodec.
+func (c *DecimalIDCodec) Decode(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+	return uint(id), nil
+}
+
+const hashIDAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// saltから決まる鍵でIDを並べ替え(Feistel構造)、saltでシャッフルした文字で表記する
+// 暗号化ではないが、saltを知らなければ連番かどうか分からない
+type HashIDCodec struct {
+	keys     [4]uint32
+	alphabet string
+}
+
+func NewHashIDCodec(salt string) *HashIDCodec {
+	sum := sha256.Sum256([]byte(salt))
+	c := &HashIDCodec{}
+	for i := range c.keys {
+		c.keys[i] = binary.BigEndian.Uint32(sum[i*4:])
+	}
+	alphabet := []byte(hashIDAlphabet)
+	random := rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[16:]), binary.BigEndian.Uint64(sum[24:])))
+	random.Shuffle(len(alphabet), func(i, j int) {
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	})
+	c.alphabet = string(alphabet)
+	return c
+}
+
+// Encode implements IIDCodec.
+func (c *HashIDCodec) Encode(id uint) string {
+	v := c.permute(uint64(id))
+	base := uint64(len(c.alphabet))
+	var b strings.Builder
+	for {
+		b.WriteByte(c.alphabet[v%base])
+		v /= base
+		if v == 0 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Decode implements IIDCodec.
+func (c *HashIDCodec) Decode(s string) (uint, error) {
+	if s == "" {
+		return 0, ErrInvalidID
+	}
+	base := uint64(len(c.alphabet))
+	var v uint64
+	for i := len(s) - 1; i >= 0; i-- {
+		digit := strings.IndexByte(c.alphabet, s[i])
+		if digit < 0 {
+			return 0, ErrInvalidID
+		}
+		next := v*base + uint64(digit)
+		if next/base != v {
+			return 0, ErrInvalidID
+		}
+		v = next
+	}
+	id := c.unpermute(v)
+	// 別の表記から同じIDにならないよう、エンコードし直して一致を確認する
+	if uint64(uint(id)) != id || c.Encode(uint(id)) != s {
+		return 0, ErrInvalidID
+	}
+	return uint(id), nil
+}
+
+func (c *HashIDCodec) permute(v uint64) uint64 {
+	left, right := uint32(v>>32), uint32(v)
+	for _, key := range c.keys {
+		left, right = right, left^round(right, key)
+	}
+	return uint64(left)<<32 | uint64(right)
+}
+
+func (c *HashIDCodec) unpermute(v uint64) uint64 {
+	left, right := uint32(v>>32), uint32(v)
+	for i := len(c.keys) - 1; i >= 0; i-- {
+		left, right = right^round(left, c.keys[i]), left
+	}
+	return uint64(left)<<32 | uint64(right)
+}
+
+func round(v uint32, key uint32) uint32 {
+	x := uint64(v^key) * 0x9E3779B97F4A7C15
+	return uint32(x>>32) ^ uint32(x)
+}
@@ -0,0 +1,89 @@
+package infra
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestHashIDCodecRoundTrip(t *testing.T) {
+	codec := NewHashIDCodec("salt")
+	for _, id := range []uint{0, 1, 2, 3, 1000, math.MaxUint32, math.MaxUint32 + 1, math.MaxUint} {
+		encoded := codec.Encode(id)
+		decoded, err := codec.Decode(encoded)
+		if err != nil {
+			t.Errorf("Decode(Encode(%d)) error = %v", id, err)
+			continue
+		}
+		if decoded != id {
+			t.Errorf("Decode(Encode(%d)) = %d", id, decoded)
+		}
+	}
+}
+
+// 連番のIDが連番の文字列にならず、saltが違えば表記も変わる
+func TestHashIDCodecHidesSequence(t *testing.T) {
+	codec := NewHashIDCodec("salt")
+	if a, b := codec.Encode(1), codec.Encode(2); a == "1" || b == "2" || strings.HasPrefix(b, a) {
+		t.Errorf("Encode(1) = %q, Encode(2) = %q, want unrelated values", a, b)
+	}
+	if a, b := codec.Encode(1), NewHashIDCodec("other").Encode(1); a == b {
+		t.Errorf("Encode(1) = %q for both salts", a)
+	}
+}
+
+func TestHashIDCodecDecodeRejects(t *testing.T) {
+	codec := NewHashIDCodec("salt")
+	encoded := codec.Encode(42)
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "empty", s: ""},
+		{name: "outside the alphabet", s: encoded + "l"},
+		{name: "leading zero digit", s: encoded + string(codec.alphabet[0])},
+		{name: "overflow", s: strings.Repeat(string(codec.alphabet[len(codec.alphabet)-1]), 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := codec.Decode(tt.s); err != ErrInvalidID {
+				t.Errorf("Decode(%q) = %d, %v, want %v", tt.s, id, err, ErrInvalidID)
+			}
+		})
+	}
+}
+
+// 書き換えた表記や別のsaltの表記は、元のIDにならない (全ての表記がどれかのIDに対応するため、拒否されるとは限らない)
+func TestHashIDCodecDecodeTampered(t *testing.T) {
+	codec := NewHashIDCodec("salt")
+	encoded := codec.Encode(42)
+	last := strings.IndexByte(codec.alphabet, encoded[len(encoded)-1])
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "last digit changed", s: encoded[:len(encoded)-1] + string(codec.alphabet[(last+1)%len(codec.alphabet)])},
+		{name: "first digit changed", s: string(codec.alphabet[(strings.IndexByte(codec.alphabet, encoded[0])+1)%len(codec.alphabet)]) + encoded[1:]},
+		{name: "decimal", s: "42"},
+		{name: "other salt", s: NewHashIDCodec("other").Encode(42)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := codec.Decode(tt.s); err == nil && id == 42 {
+				t.Errorf("Decode(%q) = 42, want another ID or an error", tt.s)
+			}
+		})
+	}
+}
+
+func TestDecimalIDCodec(t *testing.T) {
+	codec := NewIDCodec("")
+	if got := codec.Encode(42); got != "42" {
+		t.Errorf("Encode(42) = %q, want %q", got, "42")
+	}
+	for _, s := range []string{"", "abc", "-1", "18446744073709551616"} {
+		if _, err := codec.Decode(s); err != ErrInvalidID {
+			t.Errorf("Decode(%q) error = %v, want %v", s, err, ErrInvalidID)
+		}
+	}
+}
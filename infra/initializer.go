@@ -2,13 +2,31 @@ package infra
 
 import (
 	"log"
+	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// .envを読み込む前から環境変数にあったキー (設定を読み直すときも.envで上書きしない)
+var processEnv = map[string]bool{}
+
+// .envから読み込んだキー
+var fileEnv = map[string]bool{}
+
 func Initialize() {
+	for _, v := range os.Environ() {
+		key, _, _ := strings.Cut(v, "=")
+		processEnv[key] = true
+	}
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
+	for _, v := range os.Environ() {
+		key, _, _ := strings.Cut(v, "=")
+		if !processEnv[key] {
+			fileEnv[key] = true
+		}
+	}
 }
@@ -0,0 +1,83 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 起動と終了の処理 (どちらもnilにできる)
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// サブシステムの起動と終了の順序を管理する
+// 起動は登録順、終了は逆順 (先に起動したものに依存するものから止める)
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	logger  *slog.Logger
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{logger: Logger("lifecycle")}
+}
+
+func (l *Lifecycle) Register(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// 途中で失敗した場合は、起動済みのものを止めてからエラーを返す
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks[l.started:]
+	l.mu.Unlock()
+	for _, hook := range hooks {
+		if hook.Start != nil {
+			begin := time.Now()
+			if err := hook.Start(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", hook.Name, err)
+				return errors.Join(err, l.Stop(ctx))
+			}
+			l.logger.Info("started", "hook", hook.Name, "duration_ms", time.Since(begin).Milliseconds())
+		}
+		l.mu.Lock()
+		l.started++
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// 起動済みのものを逆順に止める
+// ctxの期限は全体で共有するため、1つが期限を使い切ると残りは即座にタイムアウトする
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks[:l.started]
+	l.started = 0
+	l.mu.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+		begin := time.Now()
+		err := hook.Stop(ctx)
+		duration := time.Since(begin).Milliseconds()
+		if err != nil {
+			l.logger.Error("failed to stop", "hook", hook.Name, "duration_ms", duration, "error", err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+			continue
+		}
+		l.logger.Info("stopped", "hook", hook.Name, "duration_ms", duration)
+	}
+	return errors.Join(errs...)
+}
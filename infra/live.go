@@ -0,0 +1,23 @@
+package infra
+
+import "sync/atomic"
+
+// 実行中に設定を読み直したときに差し替えられる値
+// 読む側はリクエストごとにLoadして、常に最新の値を使う
+type Live[T any] struct {
+	value atomic.Pointer[T]
+}
+
+func NewLive[T any](value T) *Live[T] {
+	l := &Live[T]{}
+	l.Store(value)
+	return l
+}
+
+func (l *Live[T]) Load() T {
+	return *l.value.Load()
+}
+
+func (l *Live[T]) Store(value T) {
+	l.value.Store(&value)
+}
@@ -0,0 +1,218 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// モジュール (repositories, servicesなど) ごとにログレベルを変えられるロガー
+// レベルは実行中にも変更できるため、再起動せずに一時的にDEBUGへ上げられる
+type logLevels struct {
+	mu        sync.RWMutex
+	base      slog.Level
+	overrides map[string]slog.Level
+	// 一時的な変更を元に戻す予定 (モジュールごと、全体は空文字)
+	reverts map[string]*logLevelRevert
+}
+
+type logLevelRevert struct {
+	timer *time.Timer
+	// 最初に一時的に変更する前のレベル (個別設定がなかった場合はhadLevelがfalse)
+	level    slog.Level
+	hadLevel bool
+}
+
+var levels = &logLevels{base: slog.LevelInfo, overrides: map[string]slog.Level{}, reverts: map[string]*logLevelRevert{}}
+
+// 出力先は共通で、レベルの判定はmoduleHandlerで行う
+var logOutput slog.Handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+func Logger(module string) *slog.Logger {
+	return slog.New(&moduleHandler{module: module, inner: logOutput}).With("module", module)
+}
+
+// LOG_LEVEL="info" と LOG_LEVELS="repositories=debug,services=warn" の形式で設定する
+// 設定を読み直した場合も、モジュールごとのレベルは設定にあるものだけに置き換える
+// (一時的な変更の予定も取り消す)
+func ConfigureLogging(config LogConfig) {
+	base, overrides, err := parseLogConfig(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	for _, v := range levels.reverts {
+		v.timer.Stop()
+	}
+	levels.reverts = map[string]*logLevelRevert{}
+	levels.base = base
+	levels.overrides = overrides
+}
+
+func parseLogConfig(config LogConfig) (slog.Level, map[string]slog.Level, error) {
+	base, err := ParseLogLevel(config.Level)
+	if err != nil {
+		return base, nil, errors.New("LOG_LEVEL " + err.Error())
+	}
+	overrides := map[string]slog.Level{}
+	for _, v := range strings.Split(config.Modules, ",") {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		module, level, ok := strings.Cut(v, "=")
+		if !ok {
+			return base, nil, errors.New("LOG_LEVELS must be module=level pairs: " + v)
+		}
+		l, err := ParseLogLevel(level)
+		if err != nil {
+			return base, nil, errors.New("LOG_LEVELS " + err.Error())
+		}
+		overrides[strings.TrimSpace(module)] = l
+	}
+	return base, overrides, nil
+}
+
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return level, fmt.Errorf("invalid log level: %q", s)
+	}
+	return level, nil
+}
+
+// moduleが空の場合は全体のレベルを変更し、変更前のレベルを返す
+// durationが0より大きい場合は、その時間が経つと元のレベルに戻してからrevertedを呼ぶ
+// 元に戻る前に重ねて変更した場合も、最初に一時的に変更する前のレベルに戻す
+func SetLogLevel(module string, level slog.Level, duration time.Duration, reverted func()) (previous slog.Level, hadPrevious bool) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	previous, hadPrevious = levels.level(module)
+	pending, ok := levels.reverts[module]
+	if ok {
+		pending.timer.Stop()
+		delete(levels.reverts, module)
+	}
+	if duration > 0 {
+		revert := &logLevelRevert{level: previous, hadLevel: hadPrevious}
+		if ok {
+			revert.level, revert.hadLevel = pending.level, pending.hadLevel
+		}
+		revert.timer = time.AfterFunc(duration, func() {
+			if levels.revert(module, revert) && reverted != nil {
+				reverted()
+			}
+		})
+		levels.reverts[module] = revert
+	}
+	levels.set(module, level)
+	return previous, hadPrevious
+}
+
+// 止める前に期限が来ていた古い予定の場合は何もせずfalseを返す
+func (l *logLevels) revert(module string, revert *logLevelRevert) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.reverts[module] != revert {
+		return false
+	}
+	delete(l.reverts, module)
+	if revert.hadLevel {
+		l.set(module, revert.level)
+	} else {
+		delete(l.overrides, module)
+	}
+	return true
+}
+
+func (l *logLevels) set(module string, level slog.Level) {
+	if module == "" {
+		l.base = level
+		return
+	}
+	l.overrides[module] = level
+}
+
+func (l *logLevels) level(module string) (slog.Level, bool) {
+	if module == "" {
+		return l.base, true
+	}
+	level, ok := l.overrides[module]
+	return level, ok
+}
+
+// モジュールに個別設定がなければokはfalse
+func LogLevel(module string) (level slog.Level, ok bool) {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.level(module)
+}
+
+// 現在の設定を返す ("default"は全体のレベル)
+func LogLevels() map[string]string {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	result := map[string]string{"default": levels.base.String()}
+	modules := make([]string, 0, len(levels.overrides))
+	for k := range levels.overrides {
+		modules = append(modules, k)
+	}
+	sort.Strings(modules)
+	for _, k := range modules {
+		result[k] = levels.overrides[k].String()
+	}
+	return result
+}
+
+func (l *logLevels) enabled(module string, level slog.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if min, ok := l.overrides[module]; ok {
+		return level >= min
+	}
+	return level >= l.base
+}
+
+type moduleHandler struct {
+	module string
+	inner  slog.Handler
+}
+
+// Enabled implements slog.Handler.
+func (h *moduleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return levels.enabled(h.module, level)
+}
+
+// Handle implements slog.Handler.
+func (h *moduleHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &moduleHandler{module: h.module, inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return &moduleHandler{module: h.module, inner: h.inner.WithGroup(name)}
+}
+
+// 追記モードでログファイルを開く ("-"の場合は標準出力)
+func OpenLogFile(path string) io.Writer {
+	if path == "-" {
+		return os.Stdout
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		panic("failed to open log file: " + err.Error())
+	}
+	return f
+}
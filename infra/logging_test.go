@@ -0,0 +1,70 @@
+package infra
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func resetLogging(t *testing.T) {
+	t.Helper()
+	ConfigureLogging(LogConfig{Level: "info"})
+	t.Cleanup(func() { ConfigureLogging(LogConfig{Level: "info"}) })
+}
+
+func waitReverted(t *testing.T, reverted chan struct{}) {
+	t.Helper()
+	select {
+	case <-reverted:
+	case <-time.After(time.Second):
+		t.Fatal("level was not reverted")
+	}
+}
+
+func TestSetLogLevelRevertsStackedChangesToOriginal(t *testing.T) {
+	resetLogging(t)
+	SetLogLevel("repositories", slog.LevelError, 0, nil)
+
+	reverted := make(chan struct{}, 2)
+	notify := func() { reverted <- struct{}{} }
+	SetLogLevel("repositories", slog.LevelDebug, time.Hour, notify)
+	previous, _ := SetLogLevel("repositories", slog.LevelWarn, 20*time.Millisecond, notify)
+	if previous != slog.LevelDebug {
+		t.Errorf("previous = %v, want %v", previous, slog.LevelDebug)
+	}
+
+	waitReverted(t, reverted)
+	if level, ok := LogLevel("repositories"); !ok || level != slog.LevelError {
+		t.Errorf("level = %v (ok=%v), want %v", level, ok, slog.LevelError)
+	}
+	if len(reverted) != 0 {
+		t.Error("cancelled revert was also run")
+	}
+}
+
+func TestSetLogLevelRevertRemovesNewOverride(t *testing.T) {
+	resetLogging(t)
+	reverted := make(chan struct{}, 1)
+	SetLogLevel("services", slog.LevelDebug, 10*time.Millisecond, func() { reverted <- struct{}{} })
+	SetLogLevel("services", slog.LevelWarn, 10*time.Millisecond, func() { reverted <- struct{}{} })
+
+	waitReverted(t, reverted)
+	if level, ok := LogLevel("services"); ok {
+		t.Errorf("override %v remains, want none", level)
+	}
+}
+
+func TestConfigureLoggingCancelsPendingReverts(t *testing.T) {
+	resetLogging(t)
+	reverted := make(chan struct{}, 1)
+	SetLogLevel("", slog.LevelDebug, 10*time.Millisecond, func() { reverted <- struct{}{} })
+	ConfigureLogging(LogConfig{Level: "warn"})
+
+	time.Sleep(50 * time.Millisecond)
+	if len(reverted) != 0 {
+		t.Error("revert ran after ConfigureLogging")
+	}
+	if level, _ := LogLevel(""); level != slog.LevelWarn {
+		t.Errorf("base level = %v, want %v", level, slog.LevelWarn)
+	}
+}
@@ -0,0 +1,20 @@
+package infra
+
+import qrcode "github.com/skip2/go-qrcode"
+
+// QRコードの画像を生成する
+type IQRCodeEncoder interface {
+	PNG(content string, size int) ([]byte, error)
+}
+
+func NewQRCodeEncoder() IQRCodeEncoder {
+	return &GoQRCodeEncoder{}
+}
+
+type GoQRCodeEncoder struct{}
+
+// PNG implements IQRCodeEncoder.
+// 印刷して読み取ることを想定して、誤り訂正レベルはM (約15%) にする
+func (e *GoQRCodeEncoder) PNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
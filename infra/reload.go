@@ -0,0 +1,225 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// 再起動せずに反映できる設定
+// これ以外の設定 (DBの接続先やトークンなど) の変更は、次に起動したときに反映する
+var reloadableSettings = map[string]bool{
+	"LOG_LEVEL":                     true,
+	"LOG_LEVELS":                    true,
+	"ACCESS_LOG_SAMPLE_RATE":        true,
+	"ACCESS_LOG_ROUTE_SAMPLE_RATES": true,
+	"MARKET_RATE_LIMIT":             true,
+	"MARKET_RATE_WINDOW":            true,
+	"LOAD_SHED_MAX_IN_FLIGHT":       true,
+	"LOAD_SHED_MAX_DB_WAIT":         true,
+	"CHAOS_LATENCY_RATE":            true,
+	"CHAOS_LATENCY":                 true,
+	"CHAOS_ERROR_RATE":              true,
+}
+
+// 読み直した結果
+type ReloadResult struct {
+	// 反映した設定のキー
+	Applied []string `json:"applied"`
+	// 変わっていたが、再起動するまで反映しない設定のキー
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// .envと環境変数から設定を読み直し、登録した反映先に渡す
+// 新しい設定が不正な場合は何も反映せず、読み直す前の状態に戻す
+type ConfigReloader struct {
+	mu      sync.Mutex
+	applies []func(config *Config)
+	logger  *slog.Logger
+}
+
+func NewConfigReloader() *ConfigReloader {
+	return &ConfigReloader{logger: Logger("config")}
+}
+
+// 反映先は検証を通った設定だけを受け取るため、エラーを返さない
+func (r *ConfigReloader) OnReload(apply func(config *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applies = append(r.applies, apply)
+}
+
+func (r *ConfigReloader) Reload() (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := LoadedSettings()
+	restoreEnv, err := reloadEnvFile()
+	if err != nil {
+		r.logger.Error("failed to reload config", "error", err)
+		return nil, err
+	}
+	config, err := loadConfigSafely()
+	if err == nil {
+		err = validateReloadable(config)
+	}
+	if err != nil {
+		restoreEnv()
+		restoreSettings(previous)
+		r.logger.Error("rejected reloaded config", "error", err)
+		return nil, err
+	}
+
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range changedSettings(previous, LoadedSettings()) {
+		if reloadableSettings[key] {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	// 再起動するまでは、実行中の値を表示し続ける
+	keepSettings(previous, result.RestartRequired)
+	for _, apply := range r.applies {
+		apply(config)
+	}
+	r.logger.Info("reloaded config", "applied", result.Applied, "restart_required", result.RestartRequired)
+	return result, nil
+}
+
+// .envを読み直して環境変数に反映し、元に戻す関数を返す
+// 起動前から環境変数にあったキーは上書きせず、.envから消えたキーは環境変数からも消す
+func reloadEnvFile() (func(), error) {
+	values, err := godotenv.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .env file: %w", err)
+	}
+	type saved struct {
+		value string
+		ok    bool
+	}
+	previous := map[string]saved{}
+	set := func(key string, value string, unset bool) {
+		if _, ok := previous[key]; !ok {
+			v, ok := os.LookupEnv(key)
+			previous[key] = saved{value: v, ok: ok}
+		}
+		if unset {
+			os.Unsetenv(key)
+			return
+		}
+		os.Setenv(key, value)
+	}
+	for key := range fileEnv {
+		if _, ok := values[key]; !ok {
+			set(key, "", true)
+		}
+	}
+	for key, value := range values {
+		if !processEnv[key] {
+			set(key, value, false)
+		}
+	}
+	previousFileEnv := fileEnv
+	fileEnv = map[string]bool{}
+	for key := range values {
+		if !processEnv[key] {
+			fileEnv[key] = true
+		}
+	}
+	return func() {
+		for key, v := range previous {
+			if v.ok {
+				os.Setenv(key, v.value)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+		fileEnv = previousFileEnv
+	}, nil
+}
+
+// getEnv*は不正な値でpanicするため、エラーに変換する
+func loadConfigSafely() (config *Config, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+	return LoadConfig(), nil
+}
+
+// 起動時には確認していない範囲も、実行中に誤った値を入れないよう確認する
+func validateReloadable(config *Config) error {
+	if _, _, err := parseLogConfig(config.Log); err != nil {
+		return err
+	}
+	rates := map[string]float64{
+		"ACCESS_LOG_SAMPLE_RATE": config.AccessLog.SampleRate,
+		"CHAOS_LATENCY_RATE":     config.Chaos.LatencyRate,
+		"CHAOS_ERROR_RATE":       config.Chaos.ErrorRate,
+	}
+	for route, rate := range config.AccessLog.RouteSampleRates {
+		rates["ACCESS_LOG_ROUTE_SAMPLE_RATES "+route] = rate
+	}
+	for key, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return errors.New(key + " must be between 0 and 1")
+		}
+	}
+	if config.MarketRateLimit.Limit > 0 && config.MarketRateLimit.Window <= 0 {
+		return errors.New("MARKET_RATE_WINDOW must be positive")
+	}
+	if config.LoadShed.MaxInFlight <= 0 {
+		return errors.New("LOAD_SHED_MAX_IN_FLIGHT must be positive")
+	}
+	return nil
+}
+
+func restoreSettings(settings []Setting) {
+	loadedSettings.mu.Lock()
+	defer loadedSettings.mu.Unlock()
+	loadedSettings.values = map[string]Setting{}
+	for _, v := range settings {
+		loadedSettings.values[v.Key] = v
+	}
+}
+
+// 指定したキーの設定を読み直す前の状態に戻す (読み直す前になかったキーは消す)
+func keepSettings(previous []Setting, keys []string) {
+	values := map[string]Setting{}
+	for _, v := range previous {
+		values[v.Key] = v
+	}
+	loadedSettings.mu.Lock()
+	defer loadedSettings.mu.Unlock()
+	for _, key := range keys {
+		if v, ok := values[key]; ok {
+			loadedSettings.values[key] = v
+		} else {
+			delete(loadedSettings.values, key)
+		}
+	}
+}
+
+// 値が変わった設定のキーを順に返す
+// 秘密の値は伏せて記録しているため、値が変わっても検知できない
+func changedSettings(before []Setting, after []Setting) []string {
+	values := map[string]Setting{}
+	for _, v := range before {
+		values[v.Key] = v
+	}
+	keys := []string{}
+	for _, v := range after {
+		if previous, ok := values[v.Key]; !ok || previous != v {
+			keys = append(keys, v.Key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
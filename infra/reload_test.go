@@ -0,0 +1,107 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// 一時ディレクトリの.envを読み直せるようにし、環境変数と読み込んだ設定を元に戻す
+func useEnvFile(t *testing.T, keys ...string) func(content string) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir() error = %v", err)
+	}
+	previousFileEnv := fileEnv
+	previousSettings := LoadedSettings()
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		fileEnv = previousFileEnv
+		restoreSettings(previousSettings)
+	})
+	// t.Setenvで終了時に元の値へ戻す
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	return func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+}
+
+func settingValue(key string) (string, bool) {
+	for _, v := range LoadedSettings() {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+func TestConfigReloaderKeepsRestartRequiredSettings(t *testing.T) {
+	writeEnv := useEnvFile(t, "LOG_LEVEL", "PUBLIC_BASE_URL")
+	// 起動時と同じく.envを読み込んでから設定を読む
+	writeEnv("LOG_LEVEL=info\nPUBLIC_BASE_URL=https://old.example.com\n")
+	if _, err := reloadEnvFile(); err != nil {
+		t.Fatalf("reloadEnvFile() error = %v", err)
+	}
+	LoadConfig()
+	reloader := NewConfigReloader()
+
+	tests := []struct {
+		name            string
+		env             string
+		applied         []string
+		restartRequired []string
+		want            map[string]string
+	}{
+		{
+			name:            "changed",
+			env:             "LOG_LEVEL=debug\nPUBLIC_BASE_URL=https://new.example.com\n",
+			applied:         []string{"LOG_LEVEL"},
+			restartRequired: []string{"PUBLIC_BASE_URL"},
+			want:            map[string]string{"LOG_LEVEL": "debug", "PUBLIC_BASE_URL": "https://old.example.com"},
+		},
+		{
+			// 反映していない変更は、読み直すたびに再起動が必要として返す
+			name:            "reloaded again",
+			env:             "LOG_LEVEL=debug\nPUBLIC_BASE_URL=https://new.example.com\n",
+			applied:         []string{},
+			restartRequired: []string{"PUBLIC_BASE_URL"},
+			want:            map[string]string{"LOG_LEVEL": "debug", "PUBLIC_BASE_URL": "https://old.example.com"},
+		},
+		{
+			name:            "reverted",
+			env:             "LOG_LEVEL=debug\nPUBLIC_BASE_URL=https://old.example.com\n",
+			applied:         []string{},
+			restartRequired: []string{},
+			want:            map[string]string{"LOG_LEVEL": "debug", "PUBLIC_BASE_URL": "https://old.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeEnv(tt.env)
+			result, err := reloader.Reload()
+			if err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+			if !slices.Equal(result.Applied, tt.applied) || !slices.Equal(result.RestartRequired, tt.restartRequired) {
+				t.Errorf("Reload() = %+v, want applied %v, restart required %v", result, tt.applied, tt.restartRequired)
+			}
+			for key, want := range tt.want {
+				if got, _ := settingValue(key); got != want {
+					t.Errorf("LoadedSettings() %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
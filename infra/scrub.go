@@ -0,0 +1,94 @@
+package infra
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ログやエラーレポートに残してはいけない値を伏せる
+const redacted = "[REDACTED]"
+
+// キー名にこれらを含む値は伏せる
+var sensitiveKeys = []string{"password", "passwd", "token", "secret", "apikey", "api_key", "api-key", "authorization", "card"}
+
+var (
+	// key=value, "key": "value" の形式
+	sensitivePairPattern = regexp.MustCompile(`(?i)("?[a-z_\-]*(?:password|passwd|token|secret|api[_-]?key|authorization|card)[a-z_\-]*"?\s*[:=]\s*)("[^"]*"|[^\s&,;]+)`)
+	// 区切り文字を含む13〜19桁の数字 (Luhnで確認してからカード番号とみなす)
+	cardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, v := range sensitiveKeys {
+		if strings.Contains(key, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// 自由な文字列から機密情報らしき値を伏せる
+func Scrub(s string) string {
+	s = sensitivePairPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := sensitivePairPattern.FindStringSubmatch(m)
+		if strings.HasPrefix(parts[2], `"`) {
+			return parts[1] + `"` + redacted + `"`
+		}
+		return parts[1] + redacted
+	})
+	return cardPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if !luhn(m) {
+			return m
+		}
+		return redacted
+	})
+}
+
+// クエリ文字列の機密情報を伏せる
+func ScrubQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Scrub(rawQuery)
+	}
+	for k, v := range values {
+		for i := range v {
+			if IsSensitiveKey(k) {
+				v[i] = redacted
+			} else {
+				v[i] = Scrub(v[i])
+			}
+		}
+	}
+	// ログで読みやすいようにエスケープを戻す
+	scrubbed, err := url.QueryUnescape(values.Encode())
+	if err != nil {
+		return values.Encode()
+	}
+	return scrubbed
+}
+
+func luhn(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
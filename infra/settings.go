@@ -0,0 +1,51 @@
+package infra
+
+import (
+	"sort"
+	"sync"
+)
+
+// 読み込んだ設定の値 (GET /admin/config で障害調査に使う)
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// 環境変数がなく、既定値を使っている
+	Default bool `json:"default"`
+}
+
+// 値を伏せる設定
+var secretSettings = map[string]bool{
+	"ID_SALT":     true,
+	"ADMIN_TOKEN": true,
+	"DB_PASSWORD": true,
+}
+
+const redactedValue = "[REDACTED]"
+
+var loadedSettings = struct {
+	mu     sync.Mutex
+	values map[string]Setting
+}{values: map[string]Setting{}}
+
+func recordSetting(key string, value string, isDefault bool) {
+	if secretSettings[key] && value != "" {
+		value = redactedValue
+	}
+	loadedSettings.mu.Lock()
+	defer loadedSettings.mu.Unlock()
+	loadedSettings.values[key] = Setting{Key: key, Value: value, Default: isDefault}
+}
+
+// これまでに読み込んだ設定をキーの順に返す (秘密の値は伏せてある)
+func LoadedSettings() []Setting {
+	loadedSettings.mu.Lock()
+	defer loadedSettings.mu.Unlock()
+	settings := make([]Setting, 0, len(loadedSettings.values))
+	for _, v := range loadedSettings.values {
+		settings = append(settings, v)
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings
+}
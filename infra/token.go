@@ -0,0 +1,80 @@
+package infra
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	mathrand "math/rand/v2"
+	"sync"
+)
+
+// ランダムなIDやトークンの生成を差し替えられるようにする
+// テストではシードを固定した実装を使い、生成される値を予測できるようにするため
+type ITokenGenerator interface {
+	// リクエストIDなどに使う16進数の文字列
+	ID() string
+	// 推測されると困る値に使うURLセーフな文字列
+	Token() string
+}
+
+// ID()は64bit、Token()は256bit
+const (
+	idBytes    = 8
+	tokenBytes = 32
+)
+
+func NewTokenGenerator() ITokenGenerator {
+	return &CryptoTokenGenerator{}
+}
+
+type CryptoTokenGenerator struct{}
+
+// ID implements ITokenGenerator.
+func (g *CryptoTokenGenerator) ID() string {
+	return hex.EncodeToString(cryptoRandom(idBytes))
+}
+
+// Token implements ITokenGenerator.
+func (g *CryptoTokenGenerator) Token() string {
+	return base64.RawURLEncoding.EncodeToString(cryptoRandom(tokenBytes))
+}
+
+func cryptoRandom(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to read random bytes: " + err.Error())
+	}
+	return b
+}
+
+// 同じシードからは常に同じ順序で同じ値を返す (テスト専用)
+type SeededTokenGenerator struct {
+	mu     sync.Mutex
+	source *mathrand.ChaCha8
+}
+
+func NewSeededTokenGenerator(seed uint64) *SeededTokenGenerator {
+	var s [32]byte
+	for i := 0; i < 8; i++ {
+		s[i] = byte(seed >> (8 * i))
+	}
+	return &SeededTokenGenerator{source: mathrand.NewChaCha8(s)}
+}
+
+// ID implements ITokenGenerator.
+func (g *SeededTokenGenerator) ID() string {
+	return hex.EncodeToString(g.read(idBytes))
+}
+
+// Token implements ITokenGenerator.
+func (g *SeededTokenGenerator) Token() string {
+	return base64.RawURLEncoding.EncodeToString(g.read(tokenBytes))
+}
+
+func (g *SeededTokenGenerator) read(n int) []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b := make([]byte, n)
+	g.source.Read(b)
+	return b
+}
@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// 画像から類似検索用のハッシュを計算するプロバイダー
+// 外部のVision APIに差し替えられるようにインターフェースにしておく
+type IVisionProvider interface {
+	Hash(r io.Reader) (string, error)
+	Distance(a string, b string) (int, error)
+}
+
+// 展開する画像の画素数の上限 (小さなファイルでも展開すると巨大になる画像を拒否する)
+const MaxImagePixels = 40_000_000
+
+// 平均ハッシュ(aHash)による知覚ハッシュの実装
+type AverageHashProvider struct{}
+
+func NewVisionProvider() IVisionProvider {
+	return &AverageHashProvider{}
+}
+
+// Hash implements IVisionProvider.
+func (p *AverageHashProvider) Hash(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	// 展開する前にヘッダーだけを読んで大きさを確かめる
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return "", errors.New("Image too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	// 8x8に縮小したグレースケール値を求める
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return "", fmt.Errorf("empty image")
+	}
+	var pixels [64]uint64
+	var total uint64
+	for y := 0; y < 8; y++ {
+		y0, y1 := cellRange(bounds.Min.Y, height, y)
+		for x := 0; x < 8; x++ {
+			x0, x1 := cellRange(bounds.Min.X, width, x)
+			var sum, count uint64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += (299*uint64(r) + 587*uint64(g) + 114*uint64(b)) / 1000
+					count++
+				}
+			}
+			pixels[y*8+x] = sum / count
+			total += pixels[y*8+x]
+		}
+	}
+
+	// 平均以上の画素を1とした64bitのハッシュ
+	average := total / 64
+	var hash uint64
+	for i, v := range pixels {
+		if v >= average {
+			hash |= 1 << uint(63-i)
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+// 8分割したi番目のセルの範囲を返す (画像が8px未満でも最低1pxは含める)
+func cellRange(min int, size int, i int) (int, int) {
+	start := min + i*size/8
+	end := min + (i+1)*size/8
+	if end <= start {
+		end = start + 1
+	}
+	if end > min+size {
+		start, end = min+size-1, min+size
+	}
+	return start, end
+}
+
+// Distance implements IVisionProvider.
+func (p *AverageHashProvider) Distance(a string, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
@@ -0,0 +1,59 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// width x heightのPNGを作る (IHDRだけを書き換えるため、画素のデータは1x1のまま)
+func pngHeader(t *testing.T, width uint32, height uint32) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.Gray{Y: 128})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	data := buf.Bytes()
+	// シグネチャ(8) + 長さ(4) + "IHDR"(4) の後に幅と高さが続く
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestAverageHashProviderHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "small image", data: pngHeader(t, 1, 1)},
+		{name: "too many pixels", data: pngHeader(t, 100_000, 100_000), wantErr: "Image too large"},
+		{name: "just over the limit", data: pngHeader(t, MaxImagePixels/1000, 1001), wantErr: "Image too large"},
+		{name: "not an image", data: []byte("not an image"), wantErr: "image: unknown format"},
+	}
+	provider := NewVisionProvider()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := provider.Hash(bytes.NewReader(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Hash() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if len(hash) != 16 {
+				t.Errorf("Hash() = %q, want 16 hex digits", hash)
+			}
+		})
+	}
+}
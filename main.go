@@ -2,19 +2,110 @@ package main
 
 // ginフレームワークをインポートします。
 import (
+	"context"
+	"errors"
+	"expvar"
 	"gin-fleamarket/controllers"
 	"gin-fleamarket/infra"
+	"gin-fleamarket/middlewares"
 	"gin-fleamarket/repositories"
 	"gin-fleamarket/services"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	infra.Initialize()
-	// ginのデフォルトのルーターを作成します。
+	// ginのルーターを作成します。
 	// ルーターは、HTTPリクエストを処理するためのエンドポイントを定義します。
-	router := gin.Default()
+	router := gin.New()
+
+	config := infra.LoadConfig()
+	clock := infra.NewSystemClock()
+	infra.ConfigureLogging(config.Log)
+	// 再起動せずに読み直せる設定 (SIGHUPか POST /admin/config/reload で読み直す)
+	reloader := infra.NewConfigReloader()
+	accessLogConfig := infra.NewLive(config.AccessLog)
+	chaosConfig := infra.NewLive(config.Chaos)
+	loadShedConfig := infra.NewLive(config.LoadShed)
+	marketRateLimit := infra.NewLive(config.MarketRateLimit)
+	reloader.OnReload(func(config *infra.Config) {
+		infra.ConfigureLogging(config.Log)
+		accessLogConfig.Store(config.AccessLog)
+		chaosConfig.Store(config.Chaos)
+		loadShedConfig.Store(config.LoadShed)
+		marketRateLimit.Store(config.MarketRateLimit)
+	})
+	// gin.Default()のテキストのログの代わりに、機密情報を伏せた構造化ログを出力する
+	router.Use(middlewares.RequestID(infra.NewTokenGenerator()), middlewares.AccessLog(accessLogConfig), gin.Recovery())
+	if config.AccessLog.CombinedPath != "" {
+		router.Use(middlewares.CombinedLog(infra.OpenLogFile(config.AccessLog.CombinedPath)))
+	}
+	router.Use(middlewares.FieldNaming(config.FieldNaming))
+	// 障害注入はリリースモードでは有効にしない
+	chaosEnabled := config.Chaos.Enabled && gin.Mode() != gin.ReleaseMode
+	if chaosEnabled {
+		router.Use(middlewares.Chaos(chaosConfig))
+	}
+
+	// 起動は登録順、終了は逆順に行う
+	lifecycle := infra.NewLifecycle()
+	db := infra.SetupDB()
+	if chaosEnabled {
+		if err := db.Use(infra.NewChaosPlugin(config.Chaos.DBErrorRate)); err != nil {
+			panic("failed to register chaos plugin: " + err.Error())
+		}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("failed to get database handle: " + err.Error())
+	}
+	lifecycle.Register(infra.Hook{Name: "database", Stop: func(ctx context.Context) error {
+		return sqlDB.Close()
+	}})
+	// DBの待ち時間と処理中のリクエスト数を監視して、過負荷時は優先度の低いリクエストを断る
+	loadShedder := middlewares.NewLoadShedder(loadShedConfig, sqlDB.Stats)
+	router.Use(loadShedder.Track())
+	lifecycle.Register(infra.Hook{Name: "load-shedder", Stop: loadShedder.Stop})
+	infra.PublishDebugVars(sqlDB, loadShedder.InFlight)
+
+	// リクエストごとにDBへ書き込まないよう、利用回数は一定間隔でまとめて書き込む
+	// 終了時はDBを閉じる前に残りを書き込む
+	featureUsageService := services.NewFeatureUsageService(repositories.NewFeatureUsageRepository(db), clock)
+	featureUsageController := controllers.NewFeatureUsageController(featureUsageService)
+	usageLogger := infra.Logger("usage")
+	usageDone := make(chan struct{})
+	lifecycle.Register(infra.Hook{
+		Name: "feature-usage",
+		Start: func(ctx context.Context) error {
+			go func() {
+				ticker := time.NewTicker(config.FeatureUsageFlushInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-usageDone:
+						return
+					case <-ticker.C:
+					}
+					if err := featureUsageService.Flush(); err != nil {
+						usageLogger.Warn("failed to flush feature usage", "error", err)
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(usageDone)
+			return featureUsageService.Flush()
+		},
+	})
+	trackUsage := middlewares.TrackUsage(featureUsageService.Record)
 
 	// ルートエンドポイントを定義します。
 	// ここでは、"/ping"というパスにGETリクエストが来たときに、
@@ -28,8 +119,6 @@ func main() {
 		})
 	})
 
-	infra.Initialize()
-	db := infra.SetupDB()
 	// items := []models.Item{
 	// 	{ID: 1, Name: "Item1", Price: 1000, Description: "Description1", SoldOut: false},
 	// 	{ID: 2, Name: "Item2", Price: 2000, Description: "Description2", SoldOut: true},
@@ -39,15 +128,172 @@ func main() {
 
 	// itemRepository := repositories.NewItemMemoryRepository(items)
 	// メモリからdbに変更
-	itemRepository := repositories.NewItemRepository(db)
+	itemRepository := repositories.NewSingleFlightItemRepository(repositories.NewItemRepository(db))
+
+	categoryRepository := repositories.NewCachedCategoryRepository(repositories.NewCategoryRepository(db), config.Cache, clock)
+	categoryService := services.NewCategoryService(categoryRepository)
+	// デプロイ直後のリクエストが一斉にDBを読みに行かないよう、起動時にキャッシュを温める
+	// 温められなくても最初のリクエストで読み込むため、起動は止めない
+	lifecycle.Register(infra.Hook{Name: "category-cache", Start: func(ctx context.Context) error {
+		if _, err := categoryService.FindAll(); err != nil {
+			infra.Logger("cache").Warn("failed to warm category cache", "error", err)
+		}
+		return nil
+	}})
+	categoryController := controllers.NewCategoryController(categoryService)
+
+	campaignRepository := repositories.NewCampaignRepository(db)
+	campaignService := services.NewCampaignService(campaignRepository, categoryService, clock)
+	campaignController := controllers.NewCampaignController(campaignService)
+
+	itemService := services.NewItemService(itemRepository, categoryService, campaignService, infra.NewVisionProvider(), clock)
+	idCodec := infra.NewIDCodec(config.IDSalt)
+	itemController := controllers.NewItemController(itemService, idCodec)
+	feedController := controllers.NewFeedController(itemService, idCodec, config.PublicBaseURL)
+
+	shortLinkRepository := repositories.NewShortLinkRepository(db)
+	shortLinkService := services.NewShortLinkService(shortLinkRepository, itemService, infra.NewTokenGenerator())
+	shortLinkController := controllers.NewShortLinkController(shortLinkService, idCodec, config.PublicBaseURL)
+	embedController := controllers.NewEmbedController(itemService, idCodec, config.PublicBaseURL)
+	qrCodeController := controllers.NewQRCodeController(itemService, shortLinkService, infra.NewQRCodeEncoder(), idCodec, config.PublicBaseURL)
+
+	// 優先度ごとにルートをまとめる
+	// 管理者向けやヘルスチェックのルートは利用回数を数えない
+	critical := router.Group("", middlewares.WithPriority(middlewares.PriorityCritical), trackUsage, loadShedder.Shed())
+	browse := router.Group("", middlewares.WithPriority(middlewares.PriorityBrowse), trackUsage, loadShedder.Shed())
+	reports := router.Group("", middlewares.WithPriority(middlewares.PriorityReports), trackUsage, loadShedder.Shed())
+	search := middlewares.WithFeature(middlewares.FeatureSearch)
+	promotions := middlewares.WithFeature(middlewares.FeaturePromotions)
+	sharing := middlewares.WithFeature(middlewares.FeatureSharing)
+	pricing := middlewares.WithFeature(middlewares.FeaturePricing)
+
+	browse.GET("/items", itemController.FindAll)
+	browse.GET("/items/search", search, itemController.Search)
+	browse.GET("/items/suggest", search, itemController.Suggest)
+	browse.GET("/items/price-suggestion", pricing, itemController.SuggestPrice)
+	browse.GET("/items/:id", itemController.FindById)
+	// HEADのボディはnet/httpが捨てる。FieldNamingなどがボディを書き換えるため、Content-Lengthは自前で数えない
+	browse.HEAD("/items", itemController.FindAll)
+	browse.HEAD("/items/:id", itemController.FindById)
+	browse.OPTIONS("/items", middlewares.Options(router))
+	browse.OPTIONS("/items/:id", middlewares.Options(router))
+	critical.POST("/items", middlewares.NewDeduplicator(config.DedupeWindow, clock).Middleware(), itemController.Create)
+	critical.PUT("/items/:id", itemController.Update)
+	// 部分更新ではキーの綴りを誤ると黙って無視されるため、未定義のキーを拒否する
+	critical.PATCH("/items/:id", middlewares.StrictBinding(), itemController.Patch)
+	critical.DELETE("/items/:id", itemController.Delete)
+	critical.POST("/items/:id/mark-sold-externally", itemController.MarkSoldExternally)
+	critical.PUT("/items/:id/image", itemController.UploadImage)
+	critical.POST("/items/:id/shortlink", sharing, shortLinkController.Create)
+	reports.POST("/items/search/by-image", search, itemController.SearchByImage)
+	reports.GET("/items/stream.ndjson", itemController.Stream)
+	browse.GET("/categories", categoryController.FindAll)
+	browse.GET("/categories/:id/attributes", categoryController.FindAttributeSchema)
+	browse.GET("/campaigns/active", promotions, campaignController.FindActive)
+	browse.GET("/feeds/items.atom", feedController.Items)
+	browse.GET("/s/:code", sharing, shortLinkController.Redirect)
+	browse.GET("/items/:id/qr.png", sharing, qrCodeController.Item)
+	// 外部のサイトから読み込まれるため、全てのオリジンを許可する
+	embed := browse.Group("/embed", middlewares.AllowAnyOrigin())
+	embed.GET("/items/:id", sharing, embedController.Item)
+	embed.OPTIONS("/items/:id", middlewares.Options(router))
+	// 共有の統計は出品者向けの情報のため、管理者トークンで保護する
+	reports.GET("/items/:id/share-stats", middlewares.AdminAuth(config.AdminToken), sharing, shortLinkController.Stats)
+	marketController := controllers.NewMarketController(itemService)
+	reports.GET("/market/sold", pricing, middlewares.NewRateLimiter(marketRateLimit, clock).Middleware(), marketController.Sold)
+
+	// 管理者向けのエンドポイント (全て管理者トークンで保護する)
+	admin := router.Group("/admin", middlewares.WithPriority(middlewares.PriorityCritical), middlewares.AdminAuth(config.AdminToken), middlewares.StrictBinding())
+	admin.POST("/categories", categoryController.Create)
+	admin.PUT("/categories/:id/move", categoryController.Move)
+	admin.PUT("/categories/:id/attributes", categoryController.UpdateAttributeSchema)
+	admin.PUT("/categories/:id/translations", categoryController.UpdateTranslations)
+	admin.POST("/categories/:id/merge", categoryController.Merge)
+	admin.POST("/categories/item-counts/rebuild", categoryController.RebuildItemCounts)
+	admin.POST("/campaigns", campaignController.Create)
+	admin.PUT("/items/:id/legal-hold", itemController.UpdateLegalHold)
+	logController := controllers.NewLogController()
+	admin.GET("/log-level", logController.FindLevels)
+	admin.PUT("/log-level", logController.UpdateLevel)
+
+	// 秘密の値を伏せた実行中の設定 (障害調査用)
+	configController := controllers.NewConfigController(map[string]bool{
+		"chaos":             chaosEnabled,
+		"loadShed":          config.LoadShed.Enabled,
+		"dedupe":            config.DedupeWindow > 0,
+		"combinedAccessLog": config.AccessLog.CombinedPath != "",
+		"legacyFieldNaming": config.FieldNaming == middlewares.FieldNamingLegacy,
+		"publicIdEncoding":  config.IDSalt != "",
+	}, map[string]string{
+		"database": "postgres",
+		"vision":   "average-hash (in-process)",
+		"qrcode":   "go-qrcode (in-process)",
+	}, reloader)
+	admin.GET("/config", configController.FindConfig)
+	admin.POST("/config/reload", configController.Reload)
+	admin.GET("/feature-usage", featureUsageController.FindUsage)
+
+	// ロードバランサーやサービスメッシュからの確認のため、優先度による制限の対象にしない
+	healthController := controllers.NewHealthController(map[string]func(ctx context.Context) error{
+		"database": sqlDB.PingContext,
+	})
+	router.GET("/readyz", healthController.Readyz)
+
+	// ゴルーチン数やプールの統計 (リークの監視用)
+	router.GET("/debug/vars", middlewares.AdminAuth(config.AdminToken), gin.WrapH(expvar.Handler()))
+
+	// SIGINT/SIGTERMを受けたら新しいリクエストの受け付けを止め、登録と逆順に終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serverLogger := infra.Logger("server")
+	server := &http.Server{Addr: "localhost:8080", Handler: router}
+	lifecycle.Register(infra.Hook{
+		Name: "http",
+		// 待ち受けに失敗した場合は起動を失敗させるため、Listenまでは同期的に行う
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serverLogger.Error("server stopped unexpectedly", "error", err)
+					stop()
+				}
+			}()
+			return nil
+		},
+		// 処理中のリクエストを待ち、期限を過ぎたら残りの接続を切る
+		Stop: func(ctx context.Context) error {
+			serverLogger.Info("draining connections", "in_flight", loadShedder.InFlight())
+			err := server.Shutdown(ctx)
+			if err != nil {
+				server.Close()
+			}
+			serverLogger.Info("connections drained", "in_flight", loadShedder.InFlight())
+			return err
+		},
+	})
 
-	itemService := services.NewItemService(itemRepository)
-	itemController := controllers.NewItemController(itemService)
-	router.GET("/items", itemController.FindAll)
-	router.GET("/items/:id", itemController.FindById)
-	router.POST("/items", itemController.Create)
-	router.PUT("/items/:id", itemController.Update)
-	router.DELETE("/items/:id", itemController.Delete)
+	if err := lifecycle.Start(ctx); err != nil {
+		panic("failed to start: " + err.Error())
+	}
+	// 不正な設定の場合は読み直す前の設定のまま動かし続ける (エラーはReloadが記録する)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			reloader.Reload()
+		}
+	}()
+	<-ctx.Done()
+	stop()
+	signal.Stop(hangup)
 
-	router.Run("localhost:8080") // 0.0.0.0:8080 でサーバーを立てます。
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := lifecycle.Stop(shutdownCtx); err != nil {
+		serverLogger.Error("shutdown did not complete", "error", err)
+		os.Exit(1)
+	}
 }
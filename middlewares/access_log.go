@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"gin-fleamarket/infra"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストごとに構造化されたアクセスログを出力する
+// 成功したリクエストは設定した割合だけ記録し、エラーは常に記録する
+// 割合は設定を読み直すと次のリクエストから変わる
+func AccessLog(config *infra.Live[infra.AccessLogConfig]) gin.HandlerFunc {
+	logger := infra.Logger("access")
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		if status < 400 && rand.Float64() >= sampleRate(config.Load(), ctx) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("query", infra.ScrubQuery(ctx.Request.URL.RawQuery)),
+			slog.Int("status", status),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.String("request_id", RequestIDOf(ctx)),
+			slog.String("trace_id", TraceIDOf(ctx)),
+		}
+		if message := errorMessageOf(ctx); message != "" {
+			attrs = append(attrs, slog.String("error", message))
+		}
+		// ハンドラーがctx.Errorで記録した内部のエラー
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", infra.Scrub(ctx.Errors.String())))
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		} else if status >= 400 {
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// "GET /items" のようにメソッドとルートで個別の割合を指定できる
+func sampleRate(config infra.AccessLogConfig, ctx *gin.Context) float64 {
+	if rate, ok := config.RouteSampleRates[ctx.Request.Method+" "+ctx.FullPath()]; ok {
+		return rate
+	}
+	return config.SampleRate
+}
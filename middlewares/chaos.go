@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"gin-fleamarket/infra"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 設定した割合のリクエストに遅延やエラーを注入する (開発・検証環境専用)
+// 有効かどうかは起動時に決まり、割合と遅延時間は設定を読み直すと変わる
+func Chaos(live *infra.Live[infra.ChaosConfig]) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		config := live.Load()
+		if rand.Float64() < config.LatencyRate {
+			time.Sleep(config.Latency)
+		}
+		if rand.Float64() < config.ErrorRate {
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Injected fault"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"fmt"
+	"gin-fleamarket/infra"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Apache/NCSAのcombined形式でアクセスログを出力する
+// 構造化ログとは別に、従来の形式を前提とした運用ツール向けに使う
+func CombinedLog(w io.Writer) gin.HandlerFunc {
+	var mu sync.Mutex
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		uri := ctx.Request.URL.Path
+		if query := infra.ScrubQuery(ctx.Request.URL.RawQuery); query != "" {
+			uri += "?" + query
+		}
+		size := "-"
+		if ctx.Writer.Size() > 0 {
+			size = strconv.Itoa(ctx.Writer.Size())
+		}
+		line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+			ctx.ClientIP(),
+			start.Format("02/Jan/2006:15:04:05 -0700"),
+			ctx.Request.Method,
+			uri,
+			ctx.Request.Proto,
+			ctx.Writer.Status(),
+			size,
+			orDash(ctx.Request.Referer()),
+			orDash(ctx.Request.UserAgent()),
+		)
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, line)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	// ダブルクォートや制御文字をエスケープする
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
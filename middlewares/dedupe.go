@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"gin-fleamarket/infra"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 同じ内容のリクエストが短時間に重複して送られた場合 (二重クリックなど) に、
+// 最初のレスポンスをそのまま返して重複した作成を防ぐ
+type Deduplicator struct {
+	window  time.Duration
+	clock   infra.IClock
+	mu      sync.Mutex
+	entries map[string]*dedupeEntry
+}
+
+type dedupeEntry struct {
+	// 最初のリクエストの処理が終わるとcloseされる
+	done    chan struct{}
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// リクエストごとに付けるヘッダー (再利用したレスポンスには今回のリクエストの値を残す)
+var perRequestHeaders = []string{"X-Request-ID", "X-Trace-ID"}
+
+func NewDeduplicator(window time.Duration, clock infra.IClock) *Deduplicator {
+	return &Deduplicator{window: window, clock: clock, entries: map[string]*dedupeEntry{}}
+}
+
+// レスポンスのボディを記録するResponseWriter
+type recordingResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (d *Deduplicator) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// Idempotency-Keyが指定されている場合はクライアントの指定に任せる
+		if d.window <= 0 || ctx.GetHeader("Idempotency-Key") != "" {
+			ctx.Next()
+			return
+		}
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid body"})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := dedupeKey(ctx, body)
+		entry, first := d.acquire(key)
+		if !first {
+			<-entry.done
+			if entry.status != 0 {
+				for k, v := range entry.header {
+					ctx.Writer.Header()[k] = v
+				}
+				ctx.Header("X-Deduplicated", "true")
+				ctx.Writer.WriteHeader(entry.status)
+				ctx.Writer.Write(entry.body)
+				ctx.Abort()
+				return
+			}
+			// 最初のリクエストが失敗していた場合は通常通り処理する
+			ctx.Next()
+			return
+		}
+
+		writer := &recordingResponseWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		// panicした場合も待っているリクエストを解放する
+		defer d.complete(key, entry, writer)
+		ctx.Next()
+	}
+}
+
+func (d *Deduplicator) acquire(key string) (*dedupeEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	for k, v := range d.entries {
+		if !v.expires.IsZero() && now.After(v.expires) {
+			delete(d.entries, k)
+		}
+	}
+	if entry, ok := d.entries[key]; ok {
+		return entry, false
+	}
+	entry := &dedupeEntry{done: make(chan struct{})}
+	d.entries[key] = entry
+	return entry, true
+}
+
+func (d *Deduplicator) complete(key string, entry *dedupeEntry, writer *recordingResponseWriter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 成功したレスポンスだけを再利用する
+	status := writer.Status()
+	if writer.Written() && status >= 200 && status < 300 {
+		entry.status = status
+		entry.header = writer.Header().Clone()
+		for _, k := range perRequestHeaders {
+			entry.header.Del(k)
+		}
+		entry.body = writer.body.Bytes()
+		entry.expires = d.clock.Now().Add(d.window)
+	} else {
+		delete(d.entries, key)
+	}
+	close(entry.done)
+}
+
+// 送信元(認証がないためクライアントIP)・ルート・ボディの内容から重複判定のキーを作る
+func dedupeKey(ctx *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(ctx.ClientIP()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(ctx.Request.Method + " " + ctx.FullPath()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,139 @@
+package middlewares_test
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testNow = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+const dedupeWindow = 10 * time.Second
+
+// main.goと同じくRequestIDの後にDeduplicatorをかけたルーター
+type dedupeServer struct {
+	router *gin.Engine
+	clock  *infra.FakeClock
+	calls  atomic.Int32
+	// nilでなければ、ハンドラーは値を受け取るまで待ってその状態で応答する
+	status chan int
+}
+
+func newDedupeServer(t *testing.T) *dedupeServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := &dedupeServer{clock: infra.NewFakeClock(testNow)}
+	s.router = gin.New()
+	s.router.Use(middlewares.RequestID(infra.NewTokenGenerator()))
+	s.router.POST("/items", middlewares.NewDeduplicator(dedupeWindow, s.clock).Middleware(), func(ctx *gin.Context) {
+		n := s.calls.Add(1)
+		status := http.StatusCreated
+		if s.status != nil {
+			status = <-s.status
+		}
+		ctx.Header("Location", "/items/1")
+		ctx.JSON(status, gin.H{"call": n})
+	})
+	return s
+}
+
+func (s *dedupeServer) post(requestId string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name":"白いシャツ"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", requestId)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeduplicatorReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		wantCalls int32
+		replayed  bool
+	}{
+		{name: "within the window", advance: dedupeWindow, wantCalls: 1, replayed: true},
+		{name: "after the window", advance: dedupeWindow + time.Second, wantCalls: 2, replayed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDedupeServer(t)
+			first := s.post("first")
+			if first.Code != http.StatusCreated {
+				t.Fatalf("first status = %d, want %d", first.Code, http.StatusCreated)
+			}
+			s.clock.Advance(tt.advance)
+			second := s.post("second")
+
+			if got := s.calls.Load(); got != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := second.Header().Get("X-Deduplicated") == "true"; got != tt.replayed {
+				t.Errorf("X-Deduplicated = %v, want %v", got, tt.replayed)
+			}
+			if second.Code != http.StatusCreated || second.Header().Get("Location") != "/items/1" {
+				t.Errorf("second status = %d, Location = %q", second.Code, second.Header().Get("Location"))
+			}
+			if tt.replayed && second.Body.String() != first.Body.String() {
+				t.Errorf("replayed body = %s, want %s", second.Body, first.Body)
+			}
+			// リクエストごとのヘッダーは最初のレスポンスから引き継がない
+			if got := second.Header().Values("X-Request-ID"); len(got) != 1 || got[0] != "second" {
+				t.Errorf("X-Request-ID = %q, want [second]", got)
+			}
+			if got := second.Header().Values("X-Trace-ID"); len(got) != 1 || got[0] == first.Header().Get("X-Trace-ID") {
+				t.Errorf("X-Trace-ID = %q, want a new one", got)
+			}
+		})
+	}
+}
+
+// 最初のリクエストが失敗した場合、待っていたリクエストは自分で処理する
+func TestDeduplicatorReleasesWaitersOnFailure(t *testing.T) {
+	s := newDedupeServer(t)
+	s.status = make(chan int)
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = s.post("first")
+	}()
+	// 最初のリクエストがハンドラーに入ってから、重複したリクエストを送る
+	for s.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[1] = s.post("second")
+	}()
+	// 重複したリクエストが待ち始めるまでの猶予
+	time.Sleep(10 * time.Millisecond)
+	if got := s.calls.Load(); got != 1 {
+		t.Fatalf("handler calls while waiting = %d, want 1", got)
+	}
+	s.status <- http.StatusInternalServerError
+	s.status <- http.StatusCreated
+	wg.Wait()
+
+	if results[0].Code != http.StatusInternalServerError {
+		t.Errorf("first status = %d, want %d", results[0].Code, http.StatusInternalServerError)
+	}
+	if results[1].Code != http.StatusCreated || results[1].Header().Get("X-Deduplicated") != "" {
+		t.Errorf("second status = %d, X-Deduplicated = %q, want %d without replay", results[1].Code, results[1].Header().Get("X-Deduplicated"), http.StatusCreated)
+	}
+	if got := s.calls.Load(); got != 2 {
+		t.Errorf("handler calls = %d, want 2", got)
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+const featureKey = "feature"
+
+// 利用状況の集計で、ルートを機能にまとめる
+const (
+	FeatureSearch     = "search"
+	FeaturePromotions = "promotions"
+	FeatureSharing    = "sharing"
+	FeaturePricing    = "pricing"
+)
+
+// ルートが属する機能を設定する
+func WithFeature(feature string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(featureKey, feature)
+		ctx.Next()
+	}
+}
+
+// ルートに一致したリクエストの利用回数を、ルートと機能ごとに数える
+// サーバー側の失敗や過負荷で断ったリクエストは利用とみなさない
+func TrackUsage(record func(endpoint string, feature string)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		if ctx.FullPath() == "" || ctx.Writer.Status() >= 500 {
+			return
+		}
+		record(ctx.Request.Method+" "+ctx.FullPath(), ctx.GetString(featureKey))
+	}
+}
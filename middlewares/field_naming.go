@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONのフィールド名の規則
+const (
+	FieldNamingCamel  = "camel"
+	FieldNamingLegacy = "legacy"
+)
+
+// camelCaseに統一する前のフィールド名 (商品・カテゴリ・キャンペーン)
+var legacyFieldNames = map[string]string{
+	"id":                       "ID",
+	"createdAt":                "CreatedAt",
+	"updatedAt":                "UpdatedAt",
+	"deletedAt":                "DeletedAt",
+	"name":                     "Name",
+	"price":                    "Price",
+	"description":              "Description",
+	"soldOut":                  "SoldOut",
+	"imageHash":                "ImageHash",
+	"categoryId":               "CategoryID",
+	"attributes":               "Attributes",
+	"metadata":                 "Metadata",
+	"closureReason":            "ClosureReason",
+	"closedAt":                 "ClosedAt",
+	"breadcrumb":               "Breadcrumb",
+	"discountedPrice":          "DiscountedPrice",
+	"campaignId":               "CampaignID",
+	"formattedPrice":           "FormattedPrice",
+	"formattedDiscountedPrice": "FormattedDiscountedPrice",
+	"parentId":                 "ParentID",
+	"path":                     "Path",
+	"position":                 "Position",
+	"attributeSchema":          "AttributeSchema",
+	"itemCount":                "ItemCount",
+	"startsAt":                 "StartsAt",
+	"endsAt":                   "EndsAt",
+	"discountPercent":          "DiscountPercent",
+	"discountAmount":           "DiscountAmount",
+}
+
+// 利用者が自由に決めたキーを持つため、中身は書き換えない
+var opaqueFields = map[string]bool{
+	"attributes": true,
+	"metadata":   true,
+}
+
+// 移行期間中、X-Field-Naming: legacy を送ったクライアントには以前のフィールド名で返す
+// 既定の規則はdefaultNamingで切り替え、移行が終わったら取り除く
+func FieldNaming(defaultNaming string) gin.HandlerFunc {
+	if defaultNaming != FieldNamingCamel && defaultNaming != FieldNamingLegacy {
+		panic("invalid field naming: " + defaultNaming)
+	}
+	return func(ctx *gin.Context) {
+		naming := ctx.GetHeader("X-Field-Naming")
+		if naming != FieldNamingCamel && naming != FieldNamingLegacy {
+			naming = defaultNaming
+		}
+		ctx.Writer = &fieldNamingWriter{ResponseWriter: ctx.Writer, legacy: naming == FieldNamingLegacy}
+		ctx.Next()
+	}
+}
+
+type fieldNamingWriter struct {
+	gin.ResponseWriter
+	legacy bool
+	varied bool
+}
+
+func (w *fieldNamingWriter) Write(data []byte) (int, error) {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+	if !w.varied {
+		w.Header().Add("Vary", "X-Field-Naming")
+		w.varied = true
+	}
+	if !w.legacy {
+		return w.ResponseWriter.Write(data)
+	}
+	// 一覧は要素ごとに分けて書き込まれ、最初の書き込みでヘッダーが送られるため先に設定する
+	w.Header().Set("Deprecation", "true")
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// 価格などの数値を丸めないようにする
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	b, err := json.Marshal(toLegacyFieldNames(body))
+	if err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(b); err != nil {
+		return 0, err
+	}
+	// 呼び出し元には渡されたバイト数を返す
+	return len(data), nil
+}
+
+func toLegacyFieldNames(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if !opaqueFields[key] {
+				child = toLegacyFieldNames(child)
+			}
+			if legacy, ok := legacyFieldNames[key]; ok {
+				key = legacy
+			}
+			renamed[key] = child
+		}
+		return renamed
+	case []interface{}:
+		for i, child := range v {
+			v[i] = toLegacyFieldNames(child)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"context"
+	"database/sql"
+	"gin-fleamarket/infra"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DBコネクションプールの待ち時間と処理中のリクエスト数から過負荷を判定する
+type LoadShedder struct {
+	// EnabledとIntervalは起動時の値を使い、上限は設定を読み直すと変わる
+	config      *infra.Live[infra.LoadShedConfig]
+	stats       func() sql.DBStats
+	inFlight    atomic.Int64
+	dbSaturated atomic.Bool
+	done        chan struct{}
+	enabled     bool
+}
+
+func NewLoadShedder(config *infra.Live[infra.LoadShedConfig], stats func() sql.DBStats) *LoadShedder {
+	s := &LoadShedder{config: config, stats: stats, done: make(chan struct{}), enabled: config.Load().Enabled}
+	if s.enabled {
+		go s.monitor()
+	}
+	return s
+}
+
+// 処理中のリクエスト数 (終了時に処理待ちのリクエストを記録するため)
+func (s *LoadShedder) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// 監視を止める
+func (s *LoadShedder) Stop(ctx context.Context) error {
+	close(s.done)
+	return nil
+}
+
+// 全てのリクエストに適用して、処理中のリクエスト数を数える
+func (s *LoadShedder) Track() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		ctx.Next()
+	}
+}
+
+// WithPriorityの後に適用して、負荷に応じてルートの優先度の低いものから503を返す
+func (s *LoadShedder) Shed() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if s.enabled && s.ShouldShed(PriorityOf(ctx)) {
+			ctx.Header("Retry-After", "1")
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Service overloaded"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 優先度ごとに断り始める負荷を変える
+//   - critical: 断らない
+//   - browse: 処理中のリクエストが上限を超えたとき、またはDBが詰まっていて上限の半分を超えたとき
+//   - reports: DBが詰まっているとき、または処理中のリクエストが上限の半分を超えたとき
+func (s *LoadShedder) ShouldShed(priority Priority) bool {
+	inFlight := s.inFlight.Load()
+	saturated := s.dbSaturated.Load()
+	maxInFlight := s.config.Load().MaxInFlight
+	switch priority {
+	case PriorityCritical:
+		return false
+	case PriorityReports:
+		return saturated || inFlight > maxInFlight/2
+	}
+	return inFlight > maxInFlight || (saturated && inFlight > maxInFlight/2)
+}
+
+// 一定間隔でプールの統計を取り、その間に発生した接続待ちの平均時間を求める
+func (s *LoadShedder) monitor() {
+	ticker := time.NewTicker(s.config.Load().Interval)
+	defer ticker.Stop()
+
+	prev := s.stats()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		current := s.stats()
+		waitCount := current.WaitCount - prev.WaitCount
+		waitDuration := current.WaitDuration - prev.WaitDuration
+		saturated := waitCount > 0 && waitDuration/time.Duration(waitCount) > s.config.Load().MaxDBWait
+		s.dbSaturated.Store(saturated)
+		prev = current
+	}
+}
@@ -0,0 +1,129 @@
+package middlewares_test
+
+import (
+	"context"
+	"database/sql"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testMaxInFlight = 4
+
+// main.goと同じくTrackを全体に、WithPriorityとShedをルートのグループにかけたルーター
+type shedServer struct {
+	router  *gin.Engine
+	shedder *middlewares.LoadShedder
+	release chan struct{}
+	holding sync.WaitGroup
+}
+
+// saturatedの場合、プールの統計は監視のたびに長い接続待ちを返す
+func newShedServer(t *testing.T, saturated bool) *shedServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var waits atomic.Int64
+	stats := func() sql.DBStats {
+		if !saturated {
+			return sql.DBStats{}
+		}
+		n := waits.Add(1)
+		return sql.DBStats{WaitCount: n, WaitDuration: time.Duration(n) * time.Second}
+	}
+	config := infra.NewLive(infra.LoadShedConfig{Enabled: true, MaxInFlight: testMaxInFlight, MaxDBWait: 100 * time.Millisecond, Interval: time.Millisecond})
+	s := &shedServer{shedder: middlewares.NewLoadShedder(config, stats), release: make(chan struct{})}
+	t.Cleanup(func() {
+		close(s.release)
+		s.holding.Wait()
+		s.shedder.Stop(context.Background())
+	})
+
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	s.router = gin.New()
+	s.router.Use(s.shedder.Track())
+	critical := s.router.Group("", middlewares.WithPriority(middlewares.PriorityCritical), s.shedder.Shed())
+	browse := s.router.Group("", middlewares.WithPriority(middlewares.PriorityBrowse), s.shedder.Shed())
+	reports := s.router.Group("", middlewares.WithPriority(middlewares.PriorityReports), s.shedder.Shed())
+	critical.GET("/critical", ok)
+	browse.GET("/browse", ok)
+	reports.GET("/reports", ok)
+	// 優先度を設定していないルートは閲覧として扱う
+	s.router.GET("/default", s.shedder.Shed(), ok)
+	critical.GET("/hold", func(ctx *gin.Context) {
+		<-s.release
+		ctx.Status(http.StatusOK)
+	})
+
+	if saturated {
+		// 監視がDBの詰まりを検知するまで待つ
+		deadline := time.Now().Add(time.Second)
+		for s.do("/reports").Code != http.StatusServiceUnavailable {
+			if time.Now().After(deadline) {
+				t.Fatal("load shedder did not detect the saturated pool")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return s
+}
+
+func (s *shedServer) do(path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// 処理が終わらないリクエストをn件抱えた状態にする
+func (s *shedServer) hold(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		s.holding.Add(1)
+		go func() {
+			defer s.holding.Done()
+			s.do("/hold")
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for s.shedder.InFlight() != int64(n) {
+		if time.Now().After(deadline) {
+			t.Fatalf("InFlight() = %d, want %d", s.shedder.InFlight(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 処理中の件数は、判定するリクエスト自身も含めて数える
+func TestLoadShedderSheds(t *testing.T) {
+	tests := []struct {
+		name      string
+		saturated bool
+		holding   int
+		status    int
+	}{
+		{name: "idle", holding: 0, status: http.StatusOK},
+		{name: "at the in-flight limit", holding: testMaxInFlight - 1, status: http.StatusOK},
+		{name: "over the in-flight limit", holding: testMaxInFlight, status: http.StatusServiceUnavailable},
+		{name: "saturated pool under half the limit", saturated: true, holding: testMaxInFlight/2 - 1, status: http.StatusOK},
+		{name: "saturated pool over half the limit", saturated: true, holding: testMaxInFlight / 2, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newShedServer(t, tt.saturated)
+			s.hold(t, tt.holding)
+			w := s.do("/default")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 登録済みのルートからAllowヘッダーを組み立ててOPTIONSに応答する
+func Options(engine *gin.Engine) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		methods := []string{}
+		for _, route := range engine.Routes() {
+			if route.Path == ctx.FullPath() {
+				methods = append(methods, route.Method)
+			}
+		}
+		sort.Strings(methods)
+		ctx.Header("Allow", strings.Join(methods, ", "))
+		ctx.Status(http.StatusNoContent)
+	}
+}
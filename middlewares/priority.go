@@ -0,0 +1,48 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+// ルートの優先度 (QoSクラス)
+// 負荷が高いときは優先度の低いクラスから処理を断る
+type Priority int
+
+const (
+	// 出品・更新など、止めてはいけない操作
+	PriorityCritical Priority = iota
+	// 一覧・詳細などの閲覧
+	PriorityBrowse
+	// 画像検索やエクスポートなど、重くて後回しにできる処理
+	PriorityReports
+)
+
+const priorityKey = "priority"
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityBrowse:
+		return "browse"
+	case PriorityReports:
+		return "reports"
+	}
+	return "unknown"
+}
+
+// ルートに優先度を設定する
+func WithPriority(priority Priority) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(priorityKey, priority)
+		ctx.Next()
+	}
+}
+
+// ルートに設定された優先度を返す (未設定の場合は閲覧扱い)
+func PriorityOf(ctx *gin.Context) Priority {
+	if v, ok := ctx.Get(priorityKey); ok {
+		if priority, ok := v.(Priority); ok {
+			return priority
+		}
+	}
+	return PriorityBrowse
+}
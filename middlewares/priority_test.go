@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// 負荷が上がるにつれて、優先度の低いルートから断る
+func TestLoadShedderPriorities(t *testing.T) {
+	tests := []struct {
+		name      string
+		saturated bool
+		holding   int
+		want      map[string]int
+	}{
+		{
+			name:    "idle",
+			holding: 0,
+			want:    map[string]int{"/critical": http.StatusOK, "/browse": http.StatusOK, "/reports": http.StatusOK},
+		},
+		{
+			name:    "over half the in-flight limit",
+			holding: testMaxInFlight / 2,
+			want:    map[string]int{"/critical": http.StatusOK, "/browse": http.StatusOK, "/reports": http.StatusServiceUnavailable},
+		},
+		{
+			name:    "over the in-flight limit",
+			holding: testMaxInFlight,
+			want:    map[string]int{"/critical": http.StatusOK, "/browse": http.StatusServiceUnavailable, "/reports": http.StatusServiceUnavailable},
+		},
+		{
+			name:      "saturated pool",
+			saturated: true,
+			holding:   0,
+			want:      map[string]int{"/critical": http.StatusOK, "/browse": http.StatusOK, "/reports": http.StatusServiceUnavailable},
+		},
+		{
+			name:      "saturated pool over half the in-flight limit",
+			saturated: true,
+			holding:   testMaxInFlight / 2,
+			want:      map[string]int{"/critical": http.StatusOK, "/browse": http.StatusServiceUnavailable, "/reports": http.StatusServiceUnavailable},
+		},
+		{
+			name:      "saturated pool far over the in-flight limit",
+			saturated: true,
+			holding:   testMaxInFlight * 2,
+			want:      map[string]int{"/critical": http.StatusOK, "/browse": http.StatusServiceUnavailable, "/reports": http.StatusServiceUnavailable},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newShedServer(t, tt.saturated)
+			s.hold(t, tt.holding)
+			for path, status := range tt.want {
+				if w := s.do(path); w.Code != status {
+					t.Errorf("GET %s status = %d, want %d", path, w.Code, status)
+				}
+			}
+		})
+	}
+}
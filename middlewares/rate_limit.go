@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"gin-fleamarket/infra"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 送信元 (認証がないためクライアントIP) ごとに、Windowの間のリクエストをLimit回までに制限する
+// 集計APIのように重いが結果の変わりにくいエンドポイントを、繰り返しの取得から守るため
+type RateLimiter struct {
+	config   *infra.Live[infra.RateLimitConfig]
+	clock    infra.IClock
+	mu       sync.Mutex
+	counters map[string]*rateCounter
+}
+
+type rateCounter struct {
+	count   int64
+	resetAt time.Time
+}
+
+// 設定を読み直した場合、数えている途中の回数はそのまま新しい上限と比べる
+func NewRateLimiter(config *infra.Live[infra.RateLimitConfig], clock infra.IClock) *RateLimiter {
+	return &RateLimiter{config: config, clock: clock, counters: map[string]*rateCounter{}}
+}
+
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		config := l.config.Load()
+		if config.Limit <= 0 {
+			ctx.Next()
+			return
+		}
+		count, resetAt := l.take(ctx.ClientIP(), config.Window)
+		now := l.clock.Now()
+		ctx.Header("X-RateLimit-Limit", strconv.FormatInt(config.Limit, 10))
+		ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(max(config.Limit-count, 0), 10))
+		if count > config.Limit {
+			// 切り上げて、再試行したときには制限が解けているようにする
+			retryAfter := int64((resetAt.Sub(now) + time.Second - 1) / time.Second)
+			ctx.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// 回数を数え、このリクエストを含めた回数と数え直す日時を返す
+func (l *RateLimiter) take(key string, window time.Duration) (int64, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	for k, v := range l.counters {
+		if !now.Before(v.resetAt) {
+			delete(l.counters, k)
+		}
+	}
+	counter, ok := l.counters[key]
+	if !ok {
+		counter = &rateCounter{resetAt: now.Add(window)}
+		l.counters[key] = counter
+	}
+	counter.count++
+	return counter.count, counter.resetAt
+}
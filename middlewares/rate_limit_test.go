@@ -0,0 +1,59 @@
+package middlewares_test
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 続けて送ったリクエストごとの結果 (advanceは送る前に進める時間)
+func TestRateLimiterWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clock := infra.NewFakeClock(testNow)
+	config := infra.NewLive(infra.RateLimitConfig{Limit: 2, Window: time.Minute})
+	router := gin.New()
+	router.GET("/market/sold", middlewares.NewRateLimiter(config, clock).Middleware(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		remoteAddr string
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{name: "first", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, remaining: "1"},
+		{name: "second", remoteAddr: "192.0.2.1:1234", status: http.StatusOK, remaining: "0"},
+		{name: "over the limit", remoteAddr: "192.0.2.1:1234", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "60"},
+		// 残り29.5秒は切り上げる
+		{name: "later in the window", advance: 30500 * time.Millisecond, remoteAddr: "192.0.2.1:1234", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "30"},
+		{name: "another client", remoteAddr: "192.0.2.2:1234", status: http.StatusOK, remaining: "1"},
+		{name: "window rolled over", advance: 29500 * time.Millisecond, remoteAddr: "192.0.2.1:1234", status: http.StatusOK, remaining: "1"},
+		// 後から数え始めた送信元は、自分の窓が終わるまで数え直さない
+		{name: "another client in its window", remoteAddr: "192.0.2.2:1234", status: http.StatusOK, remaining: "0"},
+		{name: "another client window rolled over", advance: 30500 * time.Millisecond, remoteAddr: "192.0.2.2:1234", status: http.StatusOK, remaining: "1"},
+	}
+	for _, tt := range steps {
+		clock.Advance(tt.advance)
+		req := httptest.NewRequest(http.MethodGet, "/market/sold", nil)
+		req.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("%s: X-RateLimit-Remaining = %q, want %q", tt.name, got, tt.remaining)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.retryAfter)
+		}
+	}
+}
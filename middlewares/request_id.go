@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"gin-fleamarket/infra"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDKey    = "requestId"
+	traceIDKey      = "traceId"
+	errorMessageKey = "errorMessage"
+)
+
+var (
+	// クライアントから受け取るX-Request-IDとして許可する形式
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+	// W3C Trace Contextのtraceparentヘッダー (version-traceid-parentid-flags)
+	traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// リクエストIDとトレースIDを決めてレスポンスヘッダーに付ける
+// エラーのJSONレスポンスにも両方のIDを含めて、問い合わせからログやトレースを辿れるようにする
+func RequestID(generator infra.ITokenGenerator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(requestId) {
+			requestId = generator.ID()
+		}
+		traceId := ""
+		if m := traceparentPattern.FindStringSubmatch(ctx.GetHeader("traceparent")); m != nil && m[1] != strings.Repeat("0", 32) {
+			traceId = m[1]
+		} else {
+			traceId = generator.ID() + generator.ID()
+		}
+
+		ctx.Set(requestIDKey, requestId)
+		ctx.Set(traceIDKey, traceId)
+		ctx.Header("X-Request-ID", requestId)
+		ctx.Header("X-Trace-ID", traceId)
+		ctx.Writer = &errorContextWriter{ResponseWriter: ctx.Writer, ctx: ctx}
+		ctx.Next()
+	}
+}
+
+func RequestIDOf(ctx *gin.Context) string {
+	return ctx.GetString(requestIDKey)
+}
+
+func TraceIDOf(ctx *gin.Context) string {
+	return ctx.GetString(traceIDKey)
+}
+
+// エラーレスポンスの"error"の内容 (アクセスログに含める)
+func errorMessageOf(ctx *gin.Context) string {
+	return ctx.GetString(errorMessageKey)
+}
+
+// エラーのJSONレスポンスにrequestIdとtraceIdを追加するResponseWriter
+type errorContextWriter struct {
+	gin.ResponseWriter
+	ctx *gin.Context
+}
+
+func (w *errorContextWriter) Write(data []byte) (int, error) {
+	if w.Status() < 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	if message, ok := body["error"].(string); ok {
+		w.ctx.Set(errorMessageKey, message)
+	}
+	body["requestId"] = RequestIDOf(w.ctx)
+	body["traceId"] = TraceIDOf(w.ctx)
+	b, err := json.Marshal(body)
+	if err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(b); err != nil {
+		return 0, err
+	}
+	// 呼び出し元には渡されたバイト数を返す
+	return len(data), nil
+}
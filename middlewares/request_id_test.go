@@ -0,0 +1,78 @@
+package middlewares_test
+
+import (
+	"encoding/json"
+	"gin-fleamarket/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 生成したIDを決まった値にする
+type fixedTokenGenerator struct{}
+
+func (fixedTokenGenerator) ID() string    { return "0123456789abcdef" }
+func (fixedTokenGenerator) Token() string { return "token" }
+
+func TestRequestID(t *testing.T) {
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name        string
+		requestId   string
+		traceparent string
+		wantRequest string
+		wantTrace   string
+	}{
+		{name: "generated", wantRequest: "0123456789abcdef", wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "client request ID", requestId: "client-1.retry_2", wantRequest: "client-1.retry_2", wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "request ID with invalid characters", requestId: "a b<script>", wantRequest: "0123456789abcdef", wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "request ID too long", requestId: strings.Repeat("a", 65), wantRequest: "0123456789abcdef", wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "request ID at the length limit", requestId: strings.Repeat("a", 64), wantRequest: strings.Repeat("a", 64), wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "traceparent", traceparent: "00-" + traceId + "-00f067aa0ba902b7-01", wantRequest: "0123456789abcdef", wantTrace: traceId},
+		{name: "malformed traceparent", traceparent: "00-" + traceId + "-01", wantRequest: "0123456789abcdef", wantTrace: "0123456789abcdef0123456789abcdef"},
+		{name: "all-zero trace ID", traceparent: "00-" + strings.Repeat("0", 32) + "-00f067aa0ba902b7-01", wantRequest: "0123456789abcdef", wantTrace: "0123456789abcdef0123456789abcdef"},
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.RequestID(fixedTokenGenerator{}))
+	// ハンドラーからも同じIDを参照できる
+	router.GET("/ok", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"requestId": middlewares.RequestIDOf(ctx), "traceId": middlewares.TraceIDOf(ctx)})
+	})
+	router.GET("/error", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+	})
+
+	for _, tt := range tests {
+		for _, path := range []string{"/ok", "/error"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.requestId != "" {
+					req.Header.Set("X-Request-ID", tt.requestId)
+				}
+				if tt.traceparent != "" {
+					req.Header.Set("traceparent", tt.traceparent)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if got := w.Header().Get("X-Request-ID"); got != tt.wantRequest {
+					t.Errorf("X-Request-ID = %q, want %q", got, tt.wantRequest)
+				}
+				if got := w.Header().Get("X-Trace-ID"); got != tt.wantTrace {
+					t.Errorf("X-Trace-ID = %q, want %q", got, tt.wantTrace)
+				}
+				var body map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("invalid JSON: %v\n%s", err, w.Body)
+				}
+				if body["requestId"] != tt.wantRequest || body["traceId"] != tt.wantTrace {
+					t.Errorf("body = %v, want requestId %q, traceId %q", body, tt.wantRequest, tt.wantTrace)
+				}
+			})
+		}
+	}
+}
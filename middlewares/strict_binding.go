@@ -0,0 +1,19 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+const strictBindingKey = "strictBinding"
+
+// ルートのリクエストボディを厳密に解釈する
+// 未定義のキーや重複したキーがある場合も400にして、クライアントの不具合に早く気付けるようにする
+func StrictBinding() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(strictBindingKey, true)
+		ctx.Next()
+	}
+}
+
+// ルートに厳密な解釈が設定されているか
+func IsStrictBinding(ctx *gin.Context) bool {
+	return ctx.GetBool(strictBindingKey)
+}
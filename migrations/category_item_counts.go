@@ -0,0 +1,44 @@
+package main
+
+import (
+	"gin-fleamarket/repositories"
+
+	"gorm.io/gorm"
+)
+
+// itemsの追加・削除・カテゴリの変更に合わせて、category_item_countsを更新するトリガー
+const categoryItemCountsTrigger = `
+CREATE OR REPLACE FUNCTION update_category_item_counts() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.category_id IS NOT NULL AND OLD.deleted_at IS NULL THEN
+		UPDATE category_item_counts SET item_count = item_count - 1 WHERE category_id = OLD.category_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.category_id IS NOT NULL AND NEW.deleted_at IS NULL THEN
+		INSERT INTO category_item_counts (category_id, item_count) VALUES (NEW.category_id, 1)
+		ON CONFLICT (category_id) DO UPDATE SET item_count = category_item_counts.item_count + 1;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS items_category_item_counts_insert_delete ON items;
+CREATE TRIGGER items_category_item_counts_insert_delete
+	AFTER INSERT OR DELETE ON items
+	FOR EACH ROW EXECUTE FUNCTION update_category_item_counts();
+
+-- 保存のたびに全カラムが更新されるため、カテゴリか論理削除が変わったときだけ数え直す
+DROP TRIGGER IF EXISTS items_category_item_counts_update ON items;
+CREATE TRIGGER items_category_item_counts_update
+	AFTER UPDATE ON items
+	FOR EACH ROW
+	WHEN (OLD.category_id IS DISTINCT FROM NEW.category_id OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+	EXECUTE FUNCTION update_category_item_counts();
+`
+
+// トリガーを作成し、既存の商品から集計を作り直す
+func migrateCategoryItemCounts(db *gorm.DB) error {
+	if err := db.Exec(categoryItemCountsTrigger).Error; err != nil {
+		return err
+	}
+	return repositories.NewCategoryRepository(db).RebuildItemCounts()
+}
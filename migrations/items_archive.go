@@ -0,0 +1,33 @@
+package main
+
+import (
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+// cmd/archiveで移した商品の履歴テーブル
+const itemsArchiveTable = `
+CREATE TABLE IF NOT EXISTS items_archive (LIKE items INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES)`
+
+// テーブルを作った後にitemsに追加したカラムは、models.Itemの定義から同じ型で追加する
+// (cmd/archiveはカラム名を指定して移すため、カラムの順序は揃えなくてよい)
+func migrateItemsArchive(db *gorm.DB) error {
+	if err := db.Exec(itemsArchiveTable).Error; err != nil {
+		return err
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&models.Item{}); err != nil {
+		return err
+	}
+	archive := db.Table("items_archive").Migrator()
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || archive.HasColumn(&models.Item{}, field.DBName) {
+			continue
+		}
+		if err := archive.AddColumn(&models.Item{}, field.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import "gorm.io/gorm"
+
+// 部分一致の検索と入力補完のためのトライグラムインデックス
+// ILIKE '%語%' でもインデックスが使われる
+const itemsSearchIndexes = `
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_items_name_trgm ON items USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_items_description_trgm ON items USING gin (description gin_trgm_ops);`
+
+func migrateItemsSearch(db *gorm.DB) error {
+	return db.Exec(itemsSearchIndexes).Error
+}
@@ -11,7 +11,16 @@ func main() {
 	infra.Initialize()
 	db := infra.SetupDB()
 
-	if err := db.AutoMigrate(&models.Item{}, &models.User{}); err != nil {
+	if err := db.AutoMigrate(&models.Item{}, &models.User{}, &models.Category{}, &models.Campaign{}, &models.ShortLink{}, &models.ShortLinkClick{}, &models.CategoryItemCount{}, &models.CategoryAlias{}, &models.FeatureUsage{}); err != nil {
 		panic("Failed to migrate database: ")
 	}
+	if err := migrateCategoryItemCounts(db); err != nil {
+		panic("Failed to migrate category item counts: " + err.Error())
+	}
+	if err := migrateItemsArchive(db); err != nil {
+		panic("Failed to migrate items archive: " + err.Error())
+	}
+	if err := migrateItemsSearch(db); err != nil {
+		panic("Failed to migrate items search indexes: " + err.Error())
+	}
 }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 期間限定のセール
+// 期間中は対象カテゴリ(と子孫カテゴリ)の商品の表示価格を割り引く
+type Campaign struct {
+	gorm.Model
+	Name     string    `gorm:"not null"`
+	StartsAt time.Time `gorm:"not null;index"`
+	EndsAt   time.Time `gorm:"not null;index;check:chk_campaigns_period,ends_at > starts_at"`
+	// nilの場合は全ての商品が対象
+	CategoryID *uint     `gorm:"index"`
+	Category   *Category `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	// 割引率(%)と割引額(円)は、どちらか一方を指定する
+	DiscountPercent uint `gorm:"not null;default:0;check:chk_campaigns_discount_percent,discount_percent <= 100"`
+	DiscountAmount  uint `gorm:"not null;default:0;check:chk_campaigns_discount,(discount_percent = 0) <> (discount_amount = 0)"`
+}
+
+var ErrInvalidCampaign = errors.New("invalid campaign")
+
+// BeforeSave は期間と割引の組み合わせが正しいことを保存前に確認する
+func (c *Campaign) BeforeSave(tx *gorm.DB) error {
+	if !c.EndsAt.After(c.StartsAt) {
+		return fmt.Errorf("%w: ends before it starts", ErrInvalidCampaign)
+	}
+	if (c.DiscountPercent == 0) == (c.DiscountAmount == 0) {
+		return fmt.Errorf("%w: exactly one of percent or amount is required", ErrInvalidCampaign)
+	}
+	if c.DiscountPercent > 100 {
+		return fmt.Errorf("%w: percent over 100", ErrInvalidCampaign)
+	}
+	return nil
+}
+
+// 割引後の価格 (0円未満にはならない)
+// 割合で割り引く場合の端数はDiscountRoundingに従う
+func (c *Campaign) Apply(price uint) uint {
+	discount := c.DiscountAmount
+	if c.DiscountPercent > 0 {
+		discount = Percent(price, c.DiscountPercent, DiscountRounding)
+	}
+	if discount >= price {
+		return 0
+	}
+	return price - discount
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	gorm.Model
+	Name     string `gorm:"not null"`
+	ParentID *uint  `gorm:"index"`
+	// 外部キー制約のための関連 (子のあるカテゴリは削除できない)
+	Parent *Category `gorm:"constraint:OnDelete:RESTRICT" json:"-"`
+	// ルートから自身までのIDを並べたマテリアライズドパス (例: "/1/4/")
+	Path     string `gorm:"not null;index"`
+	Position int    `gorm:"not null;default:0"`
+	// このカテゴリ(と子孫カテゴリ)の商品が持つ属性の定義
+	AttributeSchema AttributeSchema
+	// 言語ごとの名前 (Nameは日本語。翻訳がない言語ではNameを使う)
+	Translations LocalizedNames
+	// 子孫カテゴリも含めた商品数 (一覧を返すときだけ設定する)
+	ItemCount *int64 `gorm:"-" json:",omitempty"`
+}
+
+// 統合して消えたカテゴリのIDから、統合先のカテゴリへの転送
+// 古いIDで絞り込むリンクやブックマークを使えるようにする
+type CategoryAlias struct {
+	AliasID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	CategoryID uint      `gorm:"not null;index"`
+	Category   *Category `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt  time.Time
+}
+
+// カテゴリに直接属する商品数の集計
+// itemsテーブルのトリガーで更新し、ずれた場合はRebuildItemCountsで作り直す
+type CategoryItemCount struct {
+	CategoryID uint  `gorm:"primaryKey;autoIncrement:false"`
+	ItemCount  int64 `gorm:"not null;default:0"`
+}
+
+// 属性の型
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeEnum    = "enum"
+)
+
+type AttributeDefinition struct {
+	Key      string   `json:"key"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+}
+
+type AttributeSchema []AttributeDefinition
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *AttributeSchema) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*s = AttributeSchema{}
+		return nil
+	default:
+		return errors.New("failed to scan AttributeSchema")
+	}
+	return json.Unmarshal(b, s)
+}
+
+func (AttributeSchema) GormDataType() string {
+	return "jsonb"
+}
+
+// 名前を翻訳できる言語
+var TranslationLanguages = []string{"en", "zh", "ko", "de", "fr"}
+
+// 言語 (TranslationLanguagesのいずれか) ごとの名前
+type LocalizedNames map[string]string
+
+func (n LocalizedNames) Value() (driver.Value, error) {
+	if n == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (n *LocalizedNames) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*n = LocalizedNames{}
+		return nil
+	default:
+		return errors.New("failed to scan LocalizedNames")
+	}
+	return json.Unmarshal(b, n)
+}
+
+func (LocalizedNames) GormDataType() string {
+	return "jsonb"
+}
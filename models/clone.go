@@ -0,0 +1,89 @@
+package models
+
+import "slices"
+
+// 商品の深いコピー (マップ・スライス・ポインタの先もコピーする)
+// キャッシュなどで共有している値を、呼び出し元が書き換えても影響しないようにする
+func (i Item) Clone() Item {
+	i.CategoryID = clonePointer(i.CategoryID)
+	if i.Category != nil {
+		category := i.Category.Clone()
+		i.Category = &category
+	}
+	i.Attributes = i.Attributes.Clone()
+	i.Metadata = i.Metadata.Clone()
+	i.ClosedAt = clonePointer(i.ClosedAt)
+	if i.Breadcrumb != nil {
+		breadcrumb := make([]Category, len(i.Breadcrumb))
+		for j, v := range i.Breadcrumb {
+			breadcrumb[j] = v.Clone()
+		}
+		i.Breadcrumb = breadcrumb
+	}
+	i.DiscountedPrice = clonePointer(i.DiscountedPrice)
+	i.CampaignID = clonePointer(i.CampaignID)
+	return i
+}
+
+// カテゴリの深いコピー
+func (c Category) Clone() Category {
+	c.ParentID = clonePointer(c.ParentID)
+	if c.Parent != nil {
+		parent := c.Parent.Clone()
+		c.Parent = &parent
+	}
+	if c.AttributeSchema != nil {
+		schema := make(AttributeSchema, len(c.AttributeSchema))
+		for j, v := range c.AttributeSchema {
+			v.Options = slices.Clone(v.Options)
+			schema[j] = v
+		}
+		c.AttributeSchema = schema
+	}
+	if c.Translations != nil {
+		translations := make(LocalizedNames, len(c.Translations))
+		for key, v := range c.Translations {
+			translations[key] = v
+		}
+		c.Translations = translations
+	}
+	c.ItemCount = clonePointer(c.ItemCount)
+	return c
+}
+
+// 入れ子のマップとスライスもコピーする
+func (m JSONMap) Clone() JSONMap {
+	if m == nil {
+		return nil
+	}
+	return JSONMap(cloneJSONValue(map[string]interface{}(m)).(map[string]interface{}))
+}
+
+func cloneJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cloned := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			cloned[key] = cloneJSONValue(child)
+		}
+		return cloned
+	case JSONMap:
+		return v.Clone()
+	case []interface{}:
+		cloned := make([]interface{}, len(v))
+		for i, child := range v {
+			cloned[i] = cloneJSONValue(child)
+		}
+		return cloned
+	default:
+		return value
+	}
+}
+
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
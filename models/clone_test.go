@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func cloneFixture() Item {
+	closedAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	categoryId, parentId, price, campaignId, count := uint(2), uint(1), uint(900), uint(3), int64(5)
+	return Item{
+		Name:       "T-shirt",
+		Price:      1000,
+		CategoryID: &categoryId,
+		Attributes: JSONMap{"size": "M"},
+		Metadata:   JSONMap{"condition": "new", "tags": []interface{}{"a", map[string]interface{}{"b": "c"}}},
+		ClosedAt:   &closedAt,
+		Breadcrumb: []Category{{
+			Name:            "シャツ",
+			ParentID:        &parentId,
+			AttributeSchema: AttributeSchema{{Key: "size", Type: AttributeTypeEnum, Options: []string{"S", "M"}}},
+			Translations:    LocalizedNames{"en": "Shirts"},
+			ItemCount:       &count,
+		}},
+		DiscountedPrice: &price,
+		CampaignID:      &campaignId,
+	}
+}
+
+func TestItemClone(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(item *Item)
+	}{
+		{name: "category id", modify: func(item *Item) { *item.CategoryID = 9 }},
+		{name: "attributes", modify: func(item *Item) { item.Attributes["size"] = "L" }},
+		{name: "metadata", modify: func(item *Item) { item.Metadata["condition"] = "used" }},
+		{name: "nested metadata slice", modify: func(item *Item) { item.Metadata["tags"].([]interface{})[0] = "x" }},
+		{name: "nested metadata map", modify: func(item *Item) {
+			item.Metadata["tags"].([]interface{})[1].(map[string]interface{})["b"] = "x"
+		}},
+		{name: "closed at", modify: func(item *Item) { *item.ClosedAt = time.Time{} }},
+		{name: "breadcrumb", modify: func(item *Item) { item.Breadcrumb[0].Name = "靴" }},
+		{name: "breadcrumb parent id", modify: func(item *Item) { *item.Breadcrumb[0].ParentID = 9 }},
+		{name: "breadcrumb options", modify: func(item *Item) { item.Breadcrumb[0].AttributeSchema[0].Options[0] = "XL" }},
+		{name: "breadcrumb translations", modify: func(item *Item) { item.Breadcrumb[0].Translations["en"] = "Shoes" }},
+		{name: "breadcrumb item count", modify: func(item *Item) { *item.Breadcrumb[0].ItemCount = 0 }},
+		{name: "discounted price", modify: func(item *Item) { *item.DiscountedPrice = 1 }},
+		{name: "campaign id", modify: func(item *Item) { *item.CampaignID = 9 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := cloneFixture()
+			cloned := original.Clone()
+			if !reflect.DeepEqual(cloned, original) {
+				t.Fatalf("Clone() = %+v, want %+v", cloned, original)
+			}
+			tt.modify(&cloned)
+			if !reflect.DeepEqual(original, cloneFixture()) {
+				t.Errorf("modifying the clone changed the original: %+v", original)
+			}
+		})
+	}
+}
+
+func TestItemCloneNil(t *testing.T) {
+	cloned := Item{Name: "T-shirt"}.Clone()
+	if cloned.Attributes != nil || cloned.Metadata != nil || cloned.Breadcrumb != nil || cloned.CategoryID != nil {
+		t.Errorf("Clone() = %+v, want nil fields to stay nil", cloned)
+	}
+}
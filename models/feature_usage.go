@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// 利用回数を数える単位
+const (
+	// "GET /items/search" のようなメソッドとルート
+	FeatureUsageKindEndpoint = "endpoint"
+	// 検索やキャンペーンのような、複数のルートをまとめた機能
+	FeatureUsageKindFeature = "feature"
+)
+
+// 記録する時間の区切り (集計ではこれより細かく分けられない)
+const FeatureUsageBucket = time.Hour
+
+// 時間の区切りごとの利用回数
+type FeatureUsage struct {
+	ID   uint   `gorm:"primarykey"`
+	Kind string `gorm:"not null;uniqueIndex:idx_feature_usages_bucket"`
+	Name string `gorm:"not null;uniqueIndex:idx_feature_usages_bucket"`
+	// 区切りの始まりの日時
+	BucketStart time.Time `gorm:"not null;uniqueIndex:idx_feature_usages_bucket"`
+	Count       int64     `gorm:"not null;default:0"`
+}
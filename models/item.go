@@ -1,11 +1,76 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 出品できる価格の範囲 (dto.CreateItemInputのバリデーションと揃える)
+const (
+	MinItemPrice = 1
+	MaxItemPrice = 999999
+)
+
+// 画像の代替テキストの最大文字数 (dto.UpdateItemInputのバリデーションと揃える)
+const MaxImageAltTextLength = 250
+
+var ErrInvalidItem = errors.New("invalid item")
+
+// 出品を終了した理由 (プラットフォーム上で売れた場合は空)
+const ClosureReasonSoldExternally = "sold_externally"
 
 type Item struct {
 	gorm.Model
-	Name        string `gorm: "not null"`
-	Price       uint   `gorm:"not null"`
+	Name        string `gorm:"not null"`
+	Price       uint   `gorm:"not null;check:chk_items_price,price BETWEEN 1 AND 999999"`
 	Description string
-	SoldOut     bool `gorm: "not null;default:false"`
+	SoldOut     bool   `gorm:"not null;default:false"`
+	ImageHash   string `gorm:"index"`
+	// 画像の代替テキスト (空の場合は商品名を使う)
+	ImageAltText string `gorm:"not null;default:''"`
+	CategoryID   *uint  `gorm:"index"`
+	// 外部キー制約のための関連 (読み込みには使わない)
+	Category   *Category `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	Attributes JSONMap
+	Metadata   JSONMap `gorm:"index:,type:gin"`
+	// SoldOutとは別に、出品を終了した理由と日時を記録する
+	ClosureReason string `gorm:"not null;default:''"`
+	ClosedAt      *time.Time
+	// 調査などで保全が必要な商品 (削除やアーカイブの対象にしない)
+	LegalHold  bool       `gorm:"not null;default:false"`
+	Breadcrumb []Category `gorm:"-"`
+	// 開催中のキャンペーンを適用した表示価格 (保存しない)
+	DiscountedPrice *uint `gorm:"-"`
+	CampaignID      *uint `gorm:"-"`
+}
+
+// 画像の代替テキスト (画像がなければ空、設定されていなければ商品名)
+func (i Item) AltText() string {
+	if i.ImageHash == "" {
+		return ""
+	}
+	if i.ImageAltText == "" {
+		return i.Name
+	}
+	return i.ImageAltText
+}
+
+// BeforeSave はサービス層の検証をすり抜けた不正な値を保存しないための最後の確認
+// (UpdateColumnなどフックを通らない更新には効かない)
+func (i *Item) BeforeSave(tx *gorm.DB) error {
+	if strings.TrimSpace(i.Name) == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidItem)
+	}
+	if i.Price < MinItemPrice || i.Price > MaxItemPrice {
+		return fmt.Errorf("%w: price %d is out of range", ErrInvalidItem, i.Price)
+	}
+	// 出品中の商品に終了理由は付かない
+	if !i.SoldOut && (i.ClosureReason != "" || i.ClosedAt != nil) {
+		return fmt.Errorf("%w: closure recorded on an open item", ErrInvalidItem)
+	}
+	return nil
 }
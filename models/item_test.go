@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestItemBeforeSave(t *testing.T) {
+	closedAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	valid := func() Item {
+		return Item{Name: "T-shirt", Price: 1000}
+	}
+	tests := []struct {
+		name    string
+		modify  func(item *Item)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(item *Item) {}},
+		{name: "empty name", modify: func(item *Item) { item.Name = "" }, wantErr: true},
+		{name: "blank name", modify: func(item *Item) { item.Name = " \t\n" }, wantErr: true},
+		{name: "price below minimum", modify: func(item *Item) { item.Price = MinItemPrice - 1 }, wantErr: true},
+		{name: "minimum price", modify: func(item *Item) { item.Price = MinItemPrice }},
+		{name: "maximum price", modify: func(item *Item) { item.Price = MaxItemPrice }},
+		{name: "price above maximum", modify: func(item *Item) { item.Price = MaxItemPrice + 1 }, wantErr: true},
+		{name: "closure reason on an open item", modify: func(item *Item) { item.ClosureReason = ClosureReasonSoldExternally }, wantErr: true},
+		{name: "closed at on an open item", modify: func(item *Item) { item.ClosedAt = &closedAt }, wantErr: true},
+		{
+			name: "closure on a sold item",
+			modify: func(item *Item) {
+				item.SoldOut = true
+				item.ClosureReason = ClosureReasonSoldExternally
+				item.ClosedAt = &closedAt
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := valid()
+			tt.modify(&item)
+			err := item.BeforeSave(nil)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidItem) {
+					t.Errorf("BeforeSave() error = %v, want ErrInvalidItem", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("BeforeSave() error = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// JSONBカラムに保存する任意のキーと値
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		*m = JSONMap{}
+		return nil
+	default:
+		return errors.New("failed to scan JSONMap")
+	}
+	return json.Unmarshal(b, m)
+}
+
+func (JSONMap) GormDataType() string {
+	return "jsonb"
+}
+
+// XMLではキーを要素名にできるとは限らないため <entry key="...">値</entry> として出力する
+func (m JSONMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		entry := xml.StartElement{
+			Name: xml.Name{Local: "entry"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+		}
+		if err := e.EncodeElement(fmt.Sprint(m[key]), entry); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,47 @@
+package models
+
+import "math/bits"
+
+// 金額の端数処理の方法
+type RoundingMode int
+
+const (
+	// 1円未満を切り捨てる
+	RoundDown RoundingMode = iota
+	// 1円未満を切り上げる
+	RoundUp
+	// 0.5円以上を切り上げる (四捨五入)
+	RoundHalfUp
+	// 0.5円ちょうどは偶数の側に丸める (銀行丸め)
+	RoundHalfEven
+)
+
+// 端数処理の方針
+// 金額を割合で計算する処理は、ここに用途ごとの方法を決めてからPercentを使う
+const (
+	// キャンペーンの割引額は切り捨てる (表示価格が小数点以下の分だけ安くなりすぎないように)
+	DiscountRounding = RoundDown
+)
+
+// amountのpercent%の金額を、modeに従って円単位に丸める
+// amount*percentがuintに収まらない場合も、128ビットで計算するため桁あふれしない
+// (percentが100以下なら結果はamount以下になる。結果がuintに収まらない場合はpanicする)
+func Percent(amount uint, percent uint, mode RoundingMode) uint {
+	hi, lo := bits.Mul(amount, percent)
+	quotient, remainder := bits.Div(hi, lo, 100)
+	switch mode {
+	case RoundUp:
+		if remainder > 0 {
+			quotient++
+		}
+	case RoundHalfUp:
+		if remainder >= 50 {
+			quotient++
+		}
+	case RoundHalfEven:
+		if remainder > 50 || (remainder == 50 && quotient%2 == 1) {
+			quotient++
+		}
+	}
+	return quotient
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  uint
+		percent uint
+		mode    RoundingMode
+		want    uint
+	}{
+		// 1250円の10% = 125円ちょうど
+		{name: "exact down", amount: 1250, percent: 10, mode: RoundDown, want: 125},
+		{name: "exact up", amount: 1250, percent: 10, mode: RoundUp, want: 125},
+		{name: "exact half up", amount: 1250, percent: 10, mode: RoundHalfUp, want: 125},
+		{name: "exact half even", amount: 1250, percent: 10, mode: RoundHalfEven, want: 125},
+
+		// 25円の10% = 2.5円 (偶数の側は2)
+		{name: "even .5 down", amount: 25, percent: 10, mode: RoundDown, want: 2},
+		{name: "even .5 up", amount: 25, percent: 10, mode: RoundUp, want: 3},
+		{name: "even .5 half up", amount: 25, percent: 10, mode: RoundHalfUp, want: 3},
+		{name: "even .5 half even", amount: 25, percent: 10, mode: RoundHalfEven, want: 2},
+
+		// 35円の10% = 3.5円 (偶数の側は4)
+		{name: "odd .5 down", amount: 35, percent: 10, mode: RoundDown, want: 3},
+		{name: "odd .5 up", amount: 35, percent: 10, mode: RoundUp, want: 4},
+		{name: "odd .5 half up", amount: 35, percent: 10, mode: RoundHalfUp, want: 4},
+		{name: "odd .5 half even", amount: 35, percent: 10, mode: RoundHalfEven, want: 4},
+
+		// .5の前後
+		{name: "just below .5 half up", amount: 249, percent: 1, mode: RoundHalfUp, want: 2},
+		{name: "just below .5 half even", amount: 349, percent: 1, mode: RoundHalfEven, want: 3},
+		{name: "just above .5 half up", amount: 251, percent: 1, mode: RoundHalfUp, want: 3},
+		{name: "just above .5 half even", amount: 251, percent: 1, mode: RoundHalfEven, want: 3},
+		{name: "smallest fraction up", amount: 1, percent: 1, mode: RoundUp, want: 1},
+		{name: "smallest fraction down", amount: 1, percent: 1, mode: RoundDown, want: 0},
+
+		{name: "0% down", amount: 999999, percent: 0, mode: RoundDown, want: 0},
+		{name: "0% up", amount: 999999, percent: 0, mode: RoundUp, want: 0},
+		{name: "0% half up", amount: 999999, percent: 0, mode: RoundHalfUp, want: 0},
+		{name: "0% half even", amount: 999999, percent: 0, mode: RoundHalfEven, want: 0},
+		{name: "100% down", amount: 999999, percent: 100, mode: RoundDown, want: 999999},
+		{name: "100% up", amount: 999999, percent: 100, mode: RoundUp, want: 999999},
+		{name: "100% half up", amount: 999999, percent: 100, mode: RoundHalfUp, want: 999999},
+		{name: "100% half even", amount: 999999, percent: 100, mode: RoundHalfEven, want: 999999},
+		{name: "zero amount", amount: 0, percent: 50, mode: RoundUp, want: 0},
+
+		// amount*percentがuintに収まらない金額
+		{name: "max 100%", amount: math.MaxUint, percent: 100, mode: RoundDown, want: math.MaxUint},
+		{name: "max 50% down", amount: math.MaxUint, percent: 50, mode: RoundDown, want: math.MaxUint / 2},
+		{name: "max 50% up", amount: math.MaxUint, percent: 50, mode: RoundUp, want: math.MaxUint/2 + 1},
+		{name: "max 50% half up", amount: math.MaxUint, percent: 50, mode: RoundHalfUp, want: math.MaxUint/2 + 1},
+		// MaxUint/2 は奇数のため、0.5は切り上げる
+		{name: "max 50% half even", amount: math.MaxUint, percent: 50, mode: RoundHalfEven, want: math.MaxUint/2 + 1},
+		{name: "max-1 50% half even", amount: math.MaxUint - 2, percent: 50, mode: RoundHalfEven, want: (math.MaxUint - 2) / 2},
+		{name: "max 1% down", amount: math.MaxUint, percent: 1, mode: RoundDown, want: math.MaxUint / 100},
+		{name: "max 1% up", amount: math.MaxUint, percent: 1, mode: RoundUp, want: math.MaxUint/100 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percent(tt.amount, tt.percent, tt.mode); got != tt.want {
+				t.Errorf("Percent(%d, %d, %d) = %d, want %d", tt.amount, tt.percent, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
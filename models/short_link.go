@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 商品を共有するための短縮URL (/s/:code)
+type ShortLink struct {
+	gorm.Model
+	Code   string `gorm:"not null;uniqueIndex"`
+	ItemID uint   `gorm:"not null;uniqueIndex"`
+	Item   *Item  `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Clicks uint   `gorm:"not null;default:0"`
+}
+
+// 短縮URLへのアクセス記録
+type ShortLinkClick struct {
+	ID          uint       `gorm:"primarykey"`
+	ShortLinkID uint       `gorm:"not null;index"`
+	ShortLink   *ShortLink `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// 参照元のホスト名 (直接アクセスの場合は空)
+	Referrer  string
+	CreatedAt time.Time
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"gin-fleamarket/infra"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 1つの値のキャッシュ
+// TTLを過ぎてもStaleWhileRevalidateの間は古い値を返し、裏で読み込み直す
+// 同時に期限切れになっても、読み込みはsingleflightで1回にまとめる
+type cacheEntry[T any] struct {
+	policy infra.CachePolicy
+	clock  infra.IClock
+	load   func() (T, error)
+	group  singleflight.Group
+
+	mu        sync.Mutex
+	value     T
+	loaded    bool
+	fetchedAt time.Time
+	// 読み込み中に無効化された場合に、古い値で上書きしないための世代
+	generation uint64
+}
+
+func newCacheEntry[T any](policy infra.CachePolicy, clock infra.IClock, load func() (T, error)) *cacheEntry[T] {
+	return &cacheEntry[T]{policy: policy, clock: clock, load: load}
+}
+
+func (e *cacheEntry[T]) get() (T, error) {
+	e.mu.Lock()
+	value, loaded, age := e.value, e.loaded, e.clock.Now().Sub(e.fetchedAt)
+	e.mu.Unlock()
+	if loaded && age < e.policy.TTL {
+		return value, nil
+	}
+	if loaded && age < e.policy.TTL+e.policy.StaleWhileRevalidate {
+		go func() {
+			if _, err := e.refresh(); err != nil {
+				infra.Logger("cache").Warn("failed to refresh cache", "error", err)
+			}
+		}()
+		return value, nil
+	}
+	return e.refresh()
+}
+
+func (e *cacheEntry[T]) refresh() (T, error) {
+	v, err, _ := e.group.Do("", func() (interface{}, error) {
+		e.mu.Lock()
+		generation := e.generation
+		e.mu.Unlock()
+		value, err := e.load()
+		if err != nil {
+			return value, err
+		}
+		e.mu.Lock()
+		if generation == e.generation {
+			e.value, e.loaded, e.fetchedAt = value, true, e.clock.Now()
+		}
+		e.mu.Unlock()
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+func (e *cacheEntry[T]) invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var zero T
+	e.value, e.loaded = zero, false
+	e.generation++
+}
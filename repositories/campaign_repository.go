@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ICampaignRepository interface {
+	FindActive(at time.Time) (*[]models.Campaign, error)
+	Create(newCampaign models.Campaign) (*models.Campaign, error)
+}
+
+type CampaignRepository struct {
+	db *gorm.DB
+}
+
+func NewCampaignRepository(db *gorm.DB) ICampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+// FindActive implements ICampaignRepository.
+// 開始日時を含み、終了日時を含まない
+func (r *CampaignRepository) FindActive(at time.Time) (*[]models.Campaign, error) {
+	var campaigns []models.Campaign
+	result := r.db.Where("starts_at <= ? AND ends_at > ?", at, at).Order("starts_at").Find(&campaigns)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &campaigns, nil
+}
+
+// Create implements ICampaignRepository.
+func (r *CampaignRepository) Create(newCampaign models.Campaign) (*models.Campaign, error) {
+	result := r.db.Create(&newCampaign)
+	if result.Error != nil {
+		return nil, translateCampaignError(result.Error)
+	}
+	return &newCampaign, nil
+}
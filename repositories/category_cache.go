@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"maps"
+	"slices"
+)
+
+// カテゴリの一覧と商品数をプロセス内にキャッシュする
+// カテゴリはほとんど変わらないが、一覧や絞り込みのたびに読み込まれるため
+// 他のインスタンスでの変更は、それぞれのpolicyの期間が過ぎるまで反映されない
+type CachedCategoryRepository struct {
+	ICategoryRepository
+	categories *cacheEntry[[]models.Category]
+	counts     *cacheEntry[map[uint]int64]
+}
+
+func NewCachedCategoryRepository(repository ICategoryRepository, config infra.CacheConfig, clock infra.IClock) ICategoryRepository {
+	if config.Categories.TTL <= 0 && config.ItemCounts.TTL <= 0 {
+		return repository
+	}
+	return &CachedCategoryRepository{
+		ICategoryRepository: repository,
+		categories: newCacheEntry(config.Categories, clock, func() ([]models.Category, error) {
+			categories, err := repository.FindAll()
+			if err != nil {
+				return nil, err
+			}
+			return *categories, nil
+		}),
+		counts: newCacheEntry(config.ItemCounts, clock, repository.FindItemCounts),
+	}
+}
+
+// FindAll implements ICategoryRepository.
+func (r *CachedCategoryRepository) FindAll() (*[]models.Category, error) {
+	cached, err := r.categories.get()
+	if err != nil {
+		return nil, err
+	}
+	// 呼び出し元が結果を書き換えてもキャッシュに影響しないように、コピーを返す
+	categories := slices.Clone(cached)
+	return &categories, nil
+}
+
+// FindItemCounts implements ICategoryRepository.
+// 商品の出品や削除でも変わるため、カテゴリの一覧とは別の期間でキャッシュする
+func (r *CachedCategoryRepository) FindItemCounts() (map[uint]int64, error) {
+	cached, err := r.counts.get()
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(cached), nil
+}
+
+func (r *CachedCategoryRepository) invalidate() {
+	r.categories.invalidate()
+	r.counts.invalidate()
+}
+
+// Create implements ICategoryRepository.
+func (r *CachedCategoryRepository) Create(newCategory models.Category, parent *models.Category) (*models.Category, error) {
+	defer r.invalidate()
+	return r.ICategoryRepository.Create(newCategory, parent)
+}
+
+// Move implements ICategoryRepository.
+func (r *CachedCategoryRepository) Move(category models.Category, parent *models.Category) (*models.Category, error) {
+	defer r.invalidate()
+	return r.ICategoryRepository.Move(category, parent)
+}
+
+// Update implements ICategoryRepository.
+func (r *CachedCategoryRepository) Update(updateCategory models.Category) (*models.Category, error) {
+	defer r.invalidate()
+	return r.ICategoryRepository.Update(updateCategory)
+}
+
+// Merge implements ICategoryRepository.
+func (r *CachedCategoryRepository) Merge(source models.Category, target models.Category) error {
+	defer r.invalidate()
+	return r.ICategoryRepository.Merge(source, target)
+}
+
+// RebuildItemCounts implements ICategoryRepository.
+func (r *CachedCategoryRepository) RebuildItemCounts() error {
+	defer r.invalidate()
+	return r.ICategoryRepository.RebuildItemCounts()
+}
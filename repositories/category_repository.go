@@ -0,0 +1,216 @@
+// カテゴリツリーのリポジトリ(データアクセス層)
+
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+type ICategoryRepository interface {
+	FindAll() (*[]models.Category, error)
+	FindById(categoryId uint) (*models.Category, error)
+	FindByIds(categoryIds []uint) (*[]models.Category, error)
+	FindDescendants(category models.Category) (*[]models.Category, error)
+	Create(newCategory models.Category, parent *models.Category) (*models.Category, error)
+	Move(category models.Category, parent *models.Category) (*models.Category, error)
+	Update(updateCategory models.Category) (*models.Category, error)
+	Merge(source models.Category, target models.Category) error
+	FindAlias(aliasId uint) (uint, error)
+	FindItemCounts() (map[uint]int64, error)
+	RebuildItemCounts() error
+}
+
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) ICategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// FindAll implements ICategoryRepository.
+func (r *CategoryRepository) FindAll() (*[]models.Category, error) {
+	var categories []models.Category
+	// ツリーの順序はサービスで決める
+	result := r.db.Order("id").Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &categories, nil
+}
+
+// FindById implements ICategoryRepository.
+func (r *CategoryRepository) FindById(categoryId uint) (*models.Category, error) {
+	var category models.Category
+	result := r.db.First(&category, categoryId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Category not found")
+		}
+		return nil, result.Error
+	}
+	return &category, nil
+}
+
+// FindByIds implements ICategoryRepository.
+func (r *CategoryRepository) FindByIds(categoryIds []uint) (*[]models.Category, error) {
+	var categories []models.Category
+	result := r.db.Where("id IN ?", categoryIds).Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &categories, nil
+}
+
+// FindDescendants implements ICategoryRepository.
+// 自身も含めた子孫カテゴリを返す
+func (r *CategoryRepository) FindDescendants(category models.Category) (*[]models.Category, error) {
+	var categories []models.Category
+	result := r.db.Where("path LIKE ?", category.Path+"%").Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &categories, nil
+}
+
+// Create implements ICategoryRepository.
+func (r *CategoryRepository) Create(newCategory models.Category, parent *models.Category) (*models.Category, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// パスには自身のIDが含まれるため、採番後に設定する
+		if err := tx.Create(&newCategory).Error; err != nil {
+			return err
+		}
+		newCategory.Path = categoryPath(parent, newCategory.ID)
+		return tx.Model(&newCategory).Update("path", newCategory.Path).Error
+	})
+	if err != nil {
+		return nil, translateCategoryError(err)
+	}
+	return &newCategory, nil
+}
+
+// Move implements ICategoryRepository.
+// 子孫のパスもまとめて付け替える
+func (r *CategoryRepository) Move(category models.Category, parent *models.Category) (*models.Category, error) {
+	oldPath := category.Path
+	newPath := categoryPath(parent, category.ID)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Category{}).
+			Where("path LIKE ?", oldPath+"%").
+			Update("path", gorm.Expr("? || SUBSTR(path, ?)", newPath, len(oldPath)+1))
+		if result.Error != nil {
+			return result.Error
+		}
+		category.Path = newPath
+		if parent != nil {
+			category.ParentID = &parent.ID
+		} else {
+			category.ParentID = nil
+		}
+		return tx.Save(&category).Error
+	})
+	if err != nil {
+		return nil, translateCategoryError(err)
+	}
+	return &category, nil
+}
+
+// Merge implements ICategoryRepository.
+// sourceの商品・キャンペーン・子カテゴリをtargetに付け替え、sourceを削除して転送を残す
+// 商品数の集計はitemsのトリガーで更新される
+func (r *CategoryRepository) Merge(source models.Category, target models.Category) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 商品とキャンペーンの保存時の検証は、読み込んでいないモデルでは使えないため通さない
+		bulk := tx.Session(&gorm.Session{SkipHooks: true}).Unscoped()
+		if err := bulk.Model(&models.Item{}).Where("category_id = ?", source.ID).Update("category_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := bulk.Model(&models.Campaign{}).Where("category_id = ?", source.ID).Update("category_id", target.ID).Error; err != nil {
+			return err
+		}
+		// 子孫はtargetの下にそのまま移す
+		if err := tx.Model(&models.Category{}).
+			Where("path LIKE ? AND id <> ?", source.Path+"%", source.ID).
+			Update("path", gorm.Expr("? || SUBSTR(path, ?)", target.Path, len(source.Path)+1)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", source.ID).Update("parent_id", target.ID).Error; err != nil {
+			return err
+		}
+		// 以前にsourceへ統合されたカテゴリの転送先も付け替える
+		if err := tx.Model(&models.CategoryAlias{}).Where("category_id = ?", source.ID).Update("category_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.CategoryAlias{AliasID: source.ID, CategoryID: target.ID}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+}
+
+// FindAlias implements ICategoryRepository.
+// 統合されたカテゴリのIDから統合先のIDを返す
+func (r *CategoryRepository) FindAlias(aliasId uint) (uint, error) {
+	var alias models.CategoryAlias
+	result := r.db.First(&alias, "alias_id = ?", aliasId)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return 0, errors.New("Category not found")
+		}
+		return 0, result.Error
+	}
+	return alias.CategoryID, nil
+}
+
+// Update implements ICategoryRepository.
+func (r *CategoryRepository) Update(updateCategory models.Category) (*models.Category, error) {
+	result := r.db.Save(&updateCategory)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updateCategory, nil
+}
+
+// FindItemCounts implements ICategoryRepository.
+// カテゴリIDごとの、直接属する商品数
+func (r *CategoryRepository) FindItemCounts() (map[uint]int64, error) {
+	var counts []models.CategoryItemCount
+	result := r.db.Find(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	countMap := map[uint]int64{}
+	for _, v := range counts {
+		countMap[v.CategoryID] = v.ItemCount
+	}
+	return countMap, nil
+}
+
+// RebuildItemCounts implements ICategoryRepository.
+// 集計をitemsテーブルから作り直す (トリガーの導入前のデータやずれの修復用)
+func (r *CategoryRepository) RebuildItemCounts() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 作り直している間にトリガーが更新しないよう、商品の書き込みを止める
+		if err := tx.Exec("LOCK TABLE items IN SHARE MODE").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM category_item_counts").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO category_item_counts (category_id, item_count)
+			SELECT category_id, COUNT(*) FROM items
+			WHERE category_id IS NOT NULL AND deleted_at IS NULL
+			GROUP BY category_id`).Error
+	})
+}
+
+func categoryPath(parent *models.Category, categoryId uint) string {
+	if parent == nil {
+		return fmt.Sprintf("/%d/", categoryId)
+	}
+	return fmt.Sprintf("%s%d/", parent.Path, categoryId)
+}
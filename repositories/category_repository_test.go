@@ -0,0 +1,73 @@
+package repositories_test
+
+import (
+	"fmt"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/testutil/factory"
+	"gin-fleamarket/testutil/testdb"
+	"slices"
+	"testing"
+)
+
+func TestCategoryRepositoryCreateSetsPath(t *testing.T) {
+	db := testdb.Open(t)
+	categories := factory.NewCategoryFactory(db)
+	root := categories.Create(t, nil)
+	child := categories.Create(t, root)
+	grandchild := categories.Create(t, child)
+
+	tests := []struct {
+		category *models.Category
+		want     string
+	}{
+		{category: root, want: fmt.Sprintf("/%d/", root.ID)},
+		{category: child, want: fmt.Sprintf("/%d/%d/", root.ID, child.ID)},
+		{category: grandchild, want: fmt.Sprintf("/%d/%d/%d/", root.ID, child.ID, grandchild.ID)},
+	}
+	repository := repositories.NewCategoryRepository(db)
+	for _, tt := range tests {
+		if tt.category.Path != tt.want {
+			t.Errorf("created path = %q, want %q", tt.category.Path, tt.want)
+		}
+		stored, err := repository.FindById(tt.category.ID)
+		if err != nil {
+			t.Fatalf("FindById() error = %v", err)
+		}
+		if stored.Path != tt.want {
+			t.Errorf("stored path = %q, want %q", stored.Path, tt.want)
+		}
+	}
+}
+
+func TestCategoryRepositoryMoveRewritesDescendantPaths(t *testing.T) {
+	db := testdb.Open(t)
+	categories := factory.NewCategoryFactory(db)
+	from := categories.Create(t, nil)
+	to := categories.Create(t, nil)
+	moved := categories.Create(t, from)
+	child := categories.Create(t, moved)
+
+	repository := repositories.NewCategoryRepository(db)
+	if _, err := repository.Move(*moved, to); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	descendants, err := repository.FindDescendants(*to)
+	if err != nil {
+		t.Fatalf("FindDescendants() error = %v", err)
+	}
+	got := []string{}
+	for _, v := range *descendants {
+		got = append(got, v.Path)
+	}
+	slices.Sort(got)
+	want := []string{
+		fmt.Sprintf("/%d/", to.ID),
+		fmt.Sprintf("/%d/%d/", to.ID, moved.ID),
+		fmt.Sprintf("/%d/%d/%d/", to.ID, moved.ID, child.ID),
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("paths after move = %v, want %v", got, want)
+	}
+}
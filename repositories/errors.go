@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+// DBの制約違反とモデルのフック (BeforeSave) での拒否を、サービス層と同じアプリケーションのエラーに変換する
+// (サービス層の検証をすり抜けた場合でも500ではなく400として返せるようにするため)
+func translateItemError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return errors.New("Invalid category")
+	case errors.Is(err, gorm.ErrCheckConstraintViolated), errors.Is(err, models.ErrInvalidItem):
+		return errors.New("Invalid item")
+	}
+	return err
+}
+
+func translateCategoryError(err error) error {
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return errors.New("Invalid parent category")
+	}
+	return err
+}
+
+func translateCampaignError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return errors.New("Invalid category")
+	case errors.Is(err, gorm.ErrCheckConstraintViolated), errors.Is(err, models.ErrInvalidCampaign):
+		return errors.New("Invalid discount")
+	}
+	return err
+}
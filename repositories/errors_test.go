@@ -0,0 +1,31 @@
+package repositories_test
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/testutil/testdb"
+	"testing"
+	"time"
+)
+
+// BeforeSaveで拒否された値は、アプリケーションのエラーとして返す
+func TestCampaignRepositoryCreateRejectedByHook(t *testing.T) {
+	startsAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		campaign models.Campaign
+	}{
+		{name: "ends before it starts", campaign: models.Campaign{Name: "sale", StartsAt: startsAt, EndsAt: startsAt.Add(-time.Hour), DiscountPercent: 10}},
+		{name: "both discounts", campaign: models.Campaign{Name: "sale", StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), DiscountPercent: 10, DiscountAmount: 100}},
+		{name: "percent over 100", campaign: models.Campaign{Name: "sale", StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), DiscountPercent: 101}},
+	}
+	repository := repositories.NewCampaignRepository(testdb.Open(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repository.Create(tt.campaign)
+			if err == nil || err.Error() != "Invalid discount" {
+				t.Errorf("Create() error = %v, want Invalid discount", err)
+			}
+		})
+	}
+}
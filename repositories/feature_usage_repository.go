@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IFeatureUsageRepository interface {
+	// 同じ種類・名前・区切りの行があれば回数を加算する
+	Add(usages []models.FeatureUsage) error
+	// 区切りの始まりがfrom以上to未満の行
+	FindBetween(from time.Time, to time.Time) (*[]models.FeatureUsage, error)
+}
+
+type FeatureUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewFeatureUsageRepository(db *gorm.DB) IFeatureUsageRepository {
+	return &FeatureUsageRepository{db: db}
+}
+
+// Add implements IFeatureUsageRepository.
+// 複数のプロセスから同時に書き込んでも数え漏れがないように、回数はSQLで加算する
+func (r *FeatureUsageRepository) Add(usages []models.FeatureUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "name"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("feature_usages.count + excluded.count")}),
+	}).Create(&usages).Error
+}
+
+// FindBetween implements IFeatureUsageRepository.
+func (r *FeatureUsageRepository) FindBetween(from time.Time, to time.Time) (*[]models.FeatureUsage, error) {
+	var usages []models.FeatureUsage
+	result := r.db.Where("bucket_start >= ? AND bucket_start < ?", from, to).Order("bucket_start").Find(&usages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &usages, nil
+}
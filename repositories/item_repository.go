@@ -4,17 +4,79 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"gin-fleamarket/models"
+	"gin-fleamarket/repositories/scopes"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 綴りの誤りとみなす語の類似度の下限 (pg_trgmのsimilarity_thresholdの既定値と同じ)
+const similarTermThreshold = 0.3
+
+// 価格の集計の区切り (円)
+// 区切りより安い商品から順に0, 1, ...番目の区間に数える
+var PriceFacetBounds = []uint{1000, 3000, 5000, 10000, 30000}
+
+// 検索条件に一致する商品の絞り込み項目ごとの件数
+type ItemFacets struct {
+	// カテゴリIDごとの件数 (カテゴリのない商品は含めない)
+	Categories map[uint]int64
+	// PriceFacetBoundsで区切った区間ごとの件数 (len(PriceFacetBounds)+1個)
+	Prices []int64
+	// metadataのconditionごとの件数
+	Conditions map[string]int64
+}
+
+// 売れた商品の価格の分布 (Countが0の場合、価格は0)
+type SoldPriceStats struct {
+	Count  int64
+	P25    float64
+	Median float64
+	P75    float64
+}
+
+// 商品一覧の絞り込み条件
+type ItemQuery struct {
+	CategoryIds []uint
+	Attributes  map[string]string
+	Metadata    models.JSONMap
+	// 全ての語を名前か説明文に含む商品に絞り込む
+	Terms []string
+	// 作成日時の新しい順 (同時刻はIDの大きい順) に並べて、Limit件まで返す (0は全件)
+	Newest bool
+	Limit  int
+	// 指定した場合はそのページだけを返す (Newestでない場合はIDの順に並べる)
+	Page *scopes.Page
+}
+
 type IItemRepository interface {
-	FindAll() (*[]models.Item, error)
+	FindAll(query ItemQuery) (*[]models.Item, error)
+	// Pageを無視した件数
+	Count(query ItemQuery) (int64, error)
 	FindById(itemId uint) (*models.Item, error)
 	Create(newItem models.Item) (*models.Item, error)
 	Update(updateItem models.Item) (*models.Item, error)
 	Delete(itemId uint) error
+	// updatedAtが読み込んだ時点から変わっていない場合だけ書き込む (変わっていた場合は"Precondition failed")
+	UpdateIfUnmodified(updateItem models.Item, updatedAt time.Time) (*models.Item, error)
+	DeleteIfUnmodified(itemId uint, updatedAt time.Time) error
+	FindWithImageHash() (*[]models.Item, error)
+	SuggestTerms(terms []string, prefix string, limit int) ([]string, error)
+	SuggestTitles(q string, limit int) (*[]models.Item, error)
+	FindSimilarTerm(term string) (string, error)
+	CountFacets(query ItemQuery) (*ItemFacets, error)
+	// 検索条件に一致し、期間内 (from以上to未満) に売れた商品の価格の分布
+	SoldPriceStats(query ItemQuery, from time.Time, to time.Time) (*SoldPriceStats, error)
+	FindInBatches(since *time.Time, batchSize int, fn func(items []models.Item) error) error
+	// FindAllと同じ条件・順序で、全件をメモリに載せずbatchSize件ずつfnに渡す
+	FindAllInBatches(query ItemQuery, batchSize int, fn func(items []models.Item) error) error
 }
 
 type ItemMemoryRepository struct {
@@ -25,8 +87,74 @@ func NewItemMemoryRepository(items []models.Item) IItemRepository {
 	return &ItemMemoryRepository{items: items}
 }
 
-func (r *ItemMemoryRepository) FindAll() (*[]models.Item, error) {
-	return &r.items, nil
+func (r *ItemMemoryRepository) FindAll(query ItemQuery) (*[]models.Item, error) {
+	if query.CategoryIds == nil && len(query.Attributes) == 0 && len(query.Metadata) == 0 && len(query.Terms) == 0 && !query.Newest && query.Limit == 0 && query.Page == nil {
+		return &r.items, nil
+	}
+	items := []models.Item{}
+	for _, v := range r.items {
+		if query.CategoryIds != nil && (v.CategoryID == nil || !slices.Contains(query.CategoryIds, *v.CategoryID)) {
+			continue
+		}
+		if !matchAttributes(v.Attributes, query.Attributes) || !containsMetadata(v.Metadata, query.Metadata) || !matchTerms(v, query.Terms) {
+			continue
+		}
+		items = append(items, v)
+	}
+	if query.Newest {
+		sort.SliceStable(items, func(i, j int) bool {
+			if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+				return items[i].CreatedAt.After(items[j].CreatedAt)
+			}
+			return items[i].ID > items[j].ID
+		})
+	}
+	if query.Limit > 0 && len(items) > query.Limit {
+		items = items[:query.Limit]
+	}
+	if query.Page != nil {
+		if !query.Newest {
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].ID < items[j].ID
+			})
+		}
+		number := max(query.Page.Number, 1)
+		size := min(max(query.Page.Size, 1), scopes.MaxPageSize)
+		start := min((number-1)*size, len(items))
+		items = items[start:min(start+size, len(items))]
+	}
+	return &items, nil
+}
+
+func (r *ItemMemoryRepository) Count(query ItemQuery) (int64, error) {
+	query.Page = nil
+	items, err := r.FindAll(query)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(*items)), nil
+}
+
+func matchAttributes(attributes models.JSONMap, filters map[string]string) bool {
+	for key, value := range filters {
+		v, ok := attributes[key]
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func matchTerms(item models.Item, terms []string) bool {
+	name := strings.ToLower(item.Name)
+	description := strings.ToLower(item.Description)
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		if !strings.Contains(name, term) && !strings.Contains(description, term) {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *ItemMemoryRepository) FindById(itemId uint) (*models.Item, error) {
@@ -64,20 +192,301 @@ func (r *ItemMemoryRepository) Delete(itemId uint) error {
 	return errors.New("Item not found")
 }
 
+func (r *ItemMemoryRepository) UpdateIfUnmodified(updateItem models.Item, updatedAt time.Time) (*models.Item, error) {
+	for _, v := range r.items {
+		if v.ID == updateItem.ID && !v.UpdatedAt.Equal(updatedAt) {
+			return nil, errors.New("Precondition failed")
+		}
+	}
+	updateItem.UpdatedAt = time.Now()
+	return r.Update(updateItem)
+}
+
+func (r *ItemMemoryRepository) DeleteIfUnmodified(itemId uint, updatedAt time.Time) error {
+	for _, v := range r.items {
+		if v.ID == itemId && !v.UpdatedAt.Equal(updatedAt) {
+			return errors.New("Precondition failed")
+		}
+	}
+	return r.Delete(itemId)
+}
+
+func (r *ItemMemoryRepository) FindWithImageHash() (*[]models.Item, error) {
+	items := []models.Item{}
+	for _, v := range r.items {
+		if v.ImageHash != "" {
+			items = append(items, v)
+		}
+	}
+	return &items, nil
+}
+
+func (r *ItemMemoryRepository) SuggestTerms(terms []string, prefix string, limit int) ([]string, error) {
+	prefix = strings.ToLower(prefix)
+	counts := map[string]int{}
+	for _, v := range r.items {
+		if v.SoldOut || !matchTerms(models.Item{Name: v.Name}, terms) {
+			continue
+		}
+		for _, word := range strings.Fields(strings.ToLower(v.Name)) {
+			if strings.HasPrefix(word, prefix) {
+				counts[word]++
+			}
+		}
+	}
+	words := make([]string, 0, len(counts))
+	for k := range counts {
+		words = append(words, k)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	return words[:min(limit, len(words))], nil
+}
+
+func (r *ItemMemoryRepository) SuggestTitles(q string, limit int) (*[]models.Item, error) {
+	q = strings.ToLower(q)
+	items := []models.Item{}
+	for _, v := range r.items {
+		if !v.SoldOut && strings.Contains(strings.ToLower(v.Name), q) {
+			items = append(items, v)
+		}
+	}
+	// 名前が語で始まる商品を先にする
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.HasPrefix(strings.ToLower(items[i].Name), q) && !strings.HasPrefix(strings.ToLower(items[j].Name), q)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return &items, nil
+}
+
+// 名前に含まれる語のうち、トライグラムの類似度が最も高い語 (見つからない場合は空)
+func (r *ItemMemoryRepository) FindSimilarTerm(term string) (string, error) {
+	term = strings.ToLower(term)
+	best, bestScore := "", similarTermThreshold
+	for _, v := range r.items {
+		for _, word := range strings.Fields(strings.ToLower(v.Name)) {
+			if score := trigramSimilarity(word, term); score > bestScore || (score == bestScore && best != "" && word < best) {
+				best, bestScore = word, score
+			}
+		}
+	}
+	return best, nil
+}
+
+// pg_trgmのsimilarity()と同じく、前後に空白を補った3文字ずつの集合の一致率
+func trigramSimilarity(a string, b string) float64 {
+	trigrams := func(s string) map[string]bool {
+		runes := []rune("  " + s + " ")
+		set := map[string]bool{}
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+		return set
+	}
+	x, y := trigrams(a), trigrams(b)
+	shared := 0
+	for k := range x {
+		if y[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(x)+len(y)-shared)
+}
+
+func (r *ItemMemoryRepository) SoldPriceStats(query ItemQuery, from time.Time, to time.Time) (*SoldPriceStats, error) {
+	query.Newest = false
+	query.Limit = 0
+	query.Page = nil
+	items, err := r.FindAll(query)
+	if err != nil {
+		return nil, err
+	}
+	prices := []float64{}
+	for _, v := range *items {
+		soldAt := v.UpdatedAt
+		if v.ClosedAt != nil {
+			soldAt = *v.ClosedAt
+		}
+		if v.SoldOut && !soldAt.Before(from) && soldAt.Before(to) {
+			prices = append(prices, float64(v.Price))
+		}
+	}
+	sort.Float64s(prices)
+	return &SoldPriceStats{
+		Count:  int64(len(prices)),
+		P25:    percentile(prices, 0.25),
+		Median: percentile(prices, 0.5),
+		P75:    percentile(prices, 0.75),
+	}, nil
+}
+
+// PostgreSQLのpercentile_contと同じく、隣り合う値を線形補間する
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	position := p * float64(len(sorted)-1)
+	lower := int(position)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*(position-float64(lower))
+}
+
+func (r *ItemMemoryRepository) CountFacets(query ItemQuery) (*ItemFacets, error) {
+	query.Newest = false
+	query.Limit = 0
+	items, err := r.FindAll(query)
+	if err != nil {
+		return nil, err
+	}
+	facets := &ItemFacets{Categories: map[uint]int64{}, Prices: make([]int64, len(PriceFacetBounds)+1), Conditions: map[string]int64{}}
+	for _, v := range *items {
+		if v.CategoryID != nil {
+			facets.Categories[*v.CategoryID]++
+		}
+		bucket, _ := slices.BinarySearch(PriceFacetBounds, v.Price+1)
+		facets.Prices[bucket]++
+		if condition, ok := v.Metadata["condition"].(string); ok {
+			facets.Conditions[condition]++
+		}
+	}
+	return facets, nil
+}
+
+func containsMetadata(metadata models.JSONMap, filters models.JSONMap) bool {
+	for key, value := range filters {
+		if v, ok := metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ItemMemoryRepository) FindInBatches(since *time.Time, batchSize int, fn func(items []models.Item) error) error {
+	batch := []models.Item{}
+	for _, v := range r.items {
+		if since != nil && v.UpdatedAt.Before(*since) {
+			continue
+		}
+		batch = append(batch, v)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = []models.Item{}
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// FindAllInBatches implements IItemRepository.
+func (r *ItemMemoryRepository) FindAllInBatches(query ItemQuery, batchSize int, fn func(items []models.Item) error) error {
+	items, err := r.FindAll(query)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(*items); start += batchSize {
+		// 呼び出し元が書き換えても保持しているスライスに影響しないようコピーする
+		if err := fn(slices.Clone((*items)[start:min(start+batchSize, len(*items))])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type ItemRepository struct {
 	db *gorm.DB
 }
 
 // FindAll implements IItemRepository.
-func (r *ItemRepository) FindAll() (*[]models.Item, error) {
+func (r *ItemRepository) FindAll(query ItemQuery) (*[]models.Item, error) {
 	var items []models.Item
-	result := r.db.Find(&items)
+	result := r.find(query).Find(&items)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &items, nil
 }
 
+// FindAllInBatches implements IItemRepository.
+// FindInBatchesは主キーの順に読むため、並び順とページを保てるRowsで1件ずつ読み込む
+func (r *ItemRepository) FindAllInBatches(query ItemQuery, batchSize int, fn func(items []models.Item) error) error {
+	db := r.find(query).Model(&models.Item{})
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	batch := make([]models.Item, 0, batchSize)
+	for rows.Next() {
+		var item models.Item
+		if err := db.ScanRows(rows, &item); err != nil {
+			return err
+		}
+		batch = append(batch, item)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]models.Item, 0, batchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// FindAllとFindAllInBatchesの絞り込みと並び順
+func (r *ItemRepository) find(query ItemQuery) *gorm.DB {
+	db := r.db.Scopes(
+		scopes.InCategories(query.CategoryIds),
+		scopes.AttributesEqual(query.Attributes),
+		scopes.MetadataContains(query.Metadata),
+		scopes.MatchTerms(query.Terms),
+		scopes.Limit(query.Limit),
+	)
+	if query.Newest {
+		db = db.Scopes(scopes.Newest())
+	}
+	// ページの境目で商品が重複したり抜けたりしないよう、一意な順に並べる
+	if query.Page != nil {
+		if !query.Newest {
+			db = db.Order("id")
+		}
+		db = db.Scopes(scopes.Paginate(*query.Page))
+	}
+	return db
+}
+
+// Count implements IItemRepository.
+func (r *ItemRepository) Count(query ItemQuery) (int64, error) {
+	var count int64
+	result := r.db.Model(&models.Item{}).Scopes(
+		scopes.InCategories(query.CategoryIds),
+		scopes.AttributesEqual(query.Attributes),
+		scopes.MetadataContains(query.Metadata),
+		scopes.MatchTerms(query.Terms),
+	).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
 // FindById implements IItemRepository.
 func (r *ItemRepository) FindById(itemId uint) (*models.Item, error) {
 	var item models.Item
@@ -95,7 +504,7 @@ func (r *ItemRepository) FindById(itemId uint) (*models.Item, error) {
 func (r *ItemRepository) Create(newItem models.Item) (*models.Item, error) {
 	result := r.db.Create(&newItem)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, translateItemError(result.Error)
 	}
 	return &newItem, nil
 }
@@ -104,7 +513,7 @@ func (r *ItemRepository) Create(newItem models.Item) (*models.Item, error) {
 func (r *ItemRepository) Update(updateItem models.Item) (*models.Item, error) {
 	result := r.db.Save(&updateItem)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, translateItemError(result.Error)
 	}
 	return &updateItem, nil
 }
@@ -123,6 +532,180 @@ func (r *ItemRepository) Delete(itemId uint) error {
 	return nil
 }
 
+// UpdateIfUnmodified implements IItemRepository.
+// 確認と書き込みの間に他の更新が入らないよう、updated_atを条件にして1つのUPDATEで書き込む
+func (r *ItemRepository) UpdateIfUnmodified(updateItem models.Item, updatedAt time.Time) (*models.Item, error) {
+	result := r.db.Model(&updateItem).Where("updated_at = ?", updatedAt).Select("*").Updates(&updateItem)
+	if result.Error != nil {
+		return nil, translateItemError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Precondition failed")
+	}
+	return &updateItem, nil
+}
+
+// DeleteIfUnmodified implements IItemRepository.
+func (r *ItemRepository) DeleteIfUnmodified(itemId uint, updatedAt time.Time) error {
+	result := r.db.Where("updated_at = ?", updatedAt).Delete(&models.Item{}, itemId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("Precondition failed")
+	}
+	return nil
+}
+
+// FindWithImageHash implements IItemRepository.
+func (r *ItemRepository) FindWithImageHash() (*[]models.Item, error) {
+	var items []models.Item
+	result := r.db.Where("image_hash <> ?", "").Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &items, nil
+}
+
+// SuggestTerms implements IItemRepository.
+// 名前に全てのtermsを含む販売中の商品から、名前の中のprefixで始まる語を多い順に返す
+// 名前のトライグラムインデックスで候補の商品を絞ってから語に分ける
+func (r *ItemRepository) SuggestTerms(terms []string, prefix string, limit int) ([]string, error) {
+	var words []string
+	pattern := scopes.EscapeLike(strings.ToLower(prefix))
+	candidates := r.db.Model(&models.Item{}).Select("name").Scopes(scopes.Published()).Where("name ILIKE ?", "%"+pattern+"%")
+	for _, v := range terms {
+		candidates = candidates.Where("name ILIKE ?", "%"+scopes.EscapeLike(v)+"%")
+	}
+	result := r.db.Raw(`SELECT word FROM (?) AS candidates, regexp_split_to_table(lower(candidates.name), '\s+') AS word
+		WHERE word LIKE ?
+		GROUP BY word ORDER BY COUNT(*) DESC, word LIMIT ?`, candidates, pattern+"%", limit).Scan(&words)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return words, nil
+}
+
+// SuggestTitles implements IItemRepository.
+// 名前がqで始まる商品を先に、新しい順に返す (IDと名前だけを読み込む)
+func (r *ItemRepository) SuggestTitles(q string, limit int) (*[]models.Item, error) {
+	var items []models.Item
+	pattern := scopes.EscapeLike(q)
+	result := r.db.Select("id", "name").
+		Scopes(scopes.Published()).
+		Where("name ILIKE ?", "%"+pattern+"%").
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "name ILIKE ? DESC, created_at DESC", Vars: []interface{}{pattern + "%"}, WithoutParentheses: true}}).
+		Limit(limit).
+		Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &items, nil
+}
+
+// FindSimilarTerm implements IItemRepository.
+// 語を含みそうな名前をトライグラムインデックス (<%) で絞り込み、その中で最も似ている語を返す
+func (r *ItemRepository) FindSimilarTerm(term string) (string, error) {
+	var words []string
+	term = strings.ToLower(term)
+	result := r.db.Raw(`SELECT word FROM (
+			SELECT DISTINCT regexp_split_to_table(lower(name), '\s+') AS word
+			FROM items WHERE deleted_at IS NULL AND ? <% name
+		) AS vocabulary
+		WHERE similarity(word, ?) > ?
+		ORDER BY similarity(word, ?) DESC, word LIMIT 1`, term, term, similarTermThreshold, term).Scan(&words)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if len(words) == 0 {
+		return "", nil
+	}
+	return words[0], nil
+}
+
+// SoldPriceStats implements IItemRepository.
+func (r *ItemRepository) SoldPriceStats(query ItemQuery, from time.Time, to time.Time) (*SoldPriceStats, error) {
+	var stats SoldPriceStats
+	result := r.db.Model(&models.Item{}).
+		Select(`COUNT(*) AS count,
+			COALESCE(percentile_cont(0.25) WITHIN GROUP (ORDER BY price), 0) AS p25,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY price), 0) AS median,
+			COALESCE(percentile_cont(0.75) WITHIN GROUP (ORDER BY price), 0) AS p75`).
+		Scopes(
+			scopes.InCategories(query.CategoryIds),
+			scopes.AttributesEqual(query.Attributes),
+			scopes.MetadataContains(query.Metadata),
+			scopes.MatchTerms(query.Terms),
+			scopes.SoldBetween(from, to),
+		).
+		Scan(&stats)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &stats, nil
+}
+
+// CountFacets implements IItemRepository.
+// GROUPING SETSで、カテゴリ・価格帯・状態ごとの件数を1回のクエリで数える
+func (r *ItemRepository) CountFacets(query ItemQuery) (*ItemFacets, error) {
+	type row struct {
+		CategoryID        *uint
+		PriceBucket       *int
+		Condition         *string
+		Count             int64
+		GroupingCategory  int
+		GroupingPrice     int
+		GroupingCondition int
+	}
+	matched := r.db.Model(&models.Item{}).
+		Select("category_id, "+priceBucketSQL()+" AS price_bucket, metadata ->> 'condition' AS condition").
+		Scopes(
+			scopes.InCategories(query.CategoryIds),
+			scopes.AttributesEqual(query.Attributes),
+			scopes.MetadataContains(query.Metadata),
+			scopes.MatchTerms(query.Terms),
+		)
+	var rows []row
+	result := r.db.Raw(`SELECT category_id, price_bucket, condition, COUNT(*) AS count,
+			GROUPING(category_id) AS grouping_category, GROUPING(price_bucket) AS grouping_price, GROUPING(condition) AS grouping_condition
+		FROM (?) AS matched
+		GROUP BY GROUPING SETS ((category_id), (price_bucket), (condition))`, matched).Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	facets := &ItemFacets{Categories: map[uint]int64{}, Prices: make([]int64, len(PriceFacetBounds)+1), Conditions: map[string]int64{}}
+	for _, v := range rows {
+		switch {
+		case v.GroupingCategory == 0 && v.CategoryID != nil:
+			facets.Categories[*v.CategoryID] = v.Count
+		case v.GroupingPrice == 0 && v.PriceBucket != nil:
+			facets.Prices[*v.PriceBucket] = v.Count
+		case v.GroupingCondition == 0 && v.Condition != nil:
+			facets.Conditions[*v.Condition] = v.Count
+		}
+	}
+	return facets, nil
+}
+
+// 価格帯の番号を求めるSQL (価格以下の区切りの数が番号になる)
+func priceBucketSQL() string {
+	bounds := make([]string, 0, len(PriceFacetBounds))
+	for _, v := range PriceFacetBounds {
+		bounds = append(bounds, strconv.FormatUint(uint64(v), 10))
+	}
+	return "width_bucket(price, ARRAY[" + strings.Join(bounds, ",") + "])"
+}
+
+// FindInBatches implements IItemRepository.
+// 全件をメモリに載せないよう、ID順に少しずつ読み込む
+func (r *ItemRepository) FindInBatches(since *time.Time, batchSize int, fn func(items []models.Item) error) error {
+	var items []models.Item
+	result := r.db.Scopes(scopes.UpdatedSince(since)).FindInBatches(&items, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(items)
+	})
+	return result.Error
+}
+
 func NewItemRepository(db *gorm.DB) IItemRepository {
 	return &ItemRepository{db: db}
 }
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"gin-fleamarket/models"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 同じIDの商品を同時に取得するリクエストをまとめて、DBへの問い合わせを1回にする
+// 人気の商品にアクセスが集中したときの負荷を抑えるため
+type SingleFlightItemRepository struct {
+	IItemRepository
+	group singleflight.Group
+}
+
+func NewSingleFlightItemRepository(repository IItemRepository) IItemRepository {
+	return &SingleFlightItemRepository{IItemRepository: repository}
+}
+
+// FindById implements IItemRepository.
+func (r *SingleFlightItemRepository) FindById(itemId uint) (*models.Item, error) {
+	v, err, _ := r.group.Do(strconv.FormatUint(uint64(itemId), 10), func() (interface{}, error) {
+		return r.IItemRepository.FindById(itemId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 呼び出し元が結果を書き換えても他のリクエストに影響しないように、マップやスライスも含めたコピーを返す
+	item := v.(*models.Item).Clone()
+	return &item, nil
+}
@@ -0,0 +1,46 @@
+package repositories_test
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/testutil/factory"
+	"testing"
+)
+
+// 同じ商品のポインタを返し続けるリポジトリ (まとめた結果を共有している状態を再現する)
+type sharedItemRepository struct {
+	repositories.IItemRepository
+	item *models.Item
+}
+
+func (r *sharedItemRepository) FindById(itemId uint) (*models.Item, error) {
+	return r.item, nil
+}
+
+func TestSingleFlightItemRepositoryFindByIdReturnsCopy(t *testing.T) {
+	built := factory.BuildItem(func(i *models.Item) {
+		i.Attributes = models.JSONMap{"size": "M"}
+		i.Metadata = models.JSONMap{"tags": []interface{}{"a"}}
+		i.Breadcrumb = []models.Category{factory.BuildCategory(func(c *models.Category) { c.Name = "シャツ" })}
+	})
+	shared := &built
+	repository := repositories.NewSingleFlightItemRepository(&sharedItemRepository{item: shared})
+
+	item, err := repository.FindById(1)
+	if err != nil {
+		t.Fatalf("FindById() error = %v", err)
+	}
+	item.Attributes["size"] = "L"
+	item.Metadata["tags"].([]interface{})[0] = "b"
+	item.Breadcrumb[0].Name = "靴"
+
+	if shared.Attributes["size"] != "M" {
+		t.Errorf("Attributes[size] = %v, want M", shared.Attributes["size"])
+	}
+	if tag := shared.Metadata["tags"].([]interface{})[0]; tag != "a" {
+		t.Errorf("Metadata[tags][0] = %v, want a", tag)
+	}
+	if shared.Breadcrumb[0].Name != "シャツ" {
+		t.Errorf("Breadcrumb[0].Name = %q, want シャツ", shared.Breadcrumb[0].Name)
+	}
+}
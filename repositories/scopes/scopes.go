@@ -0,0 +1,121 @@
+// リポジトリで組み合わせて使うクエリの部品 (gormのScopes)
+// 例: db.Scopes(scopes.Published(), scopes.InCategories(categoryIds)).Find(&items)
+
+package scopes
+
+import (
+	"gin-fleamarket/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Scope = func(db *gorm.DB) *gorm.DB
+
+// 1ページの件数の上限
+const MaxPageSize = 100
+
+// 販売中 (売り切れていない) の商品
+func Published() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("sold_out = ?", false)
+	}
+}
+
+// 指定したカテゴリのいずれかに属する商品 (nilの場合は絞り込まない)
+func InCategories(categoryIds []uint) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if categoryIds == nil {
+			return db
+		}
+		return db.Where("category_id IN ?", categoryIds)
+	}
+}
+
+// 属性の値が一致する商品 (値は文字列として比較する)
+func AttributesEqual(attributes map[string]string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		for key, value := range attributes {
+			db = db.Where("attributes ->> ? = ?", key, value)
+		}
+		return db
+	}
+}
+
+// metadataが指定したキーと値を全て含む商品
+// @> での包含検索はGINインデックスが使われる
+func MetadataContains(metadata models.JSONMap) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(metadata) == 0 {
+			return db
+		}
+		return db.Where("metadata @> ?::jsonb", metadata)
+	}
+}
+
+// 全ての語を名前か説明文に含む商品 (大文字小文字を区別しない)
+func MatchTerms(terms []string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, term := range terms {
+			pattern := "%" + EscapeLike(term) + "%"
+			db = db.Where("(name ILIKE ? OR description ILIKE ?)", pattern, pattern)
+		}
+		return db
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// LIKEのワイルドカードを文字として扱う
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func UpdatedSince(since *time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if since == nil {
+			return db
+		}
+		return db.Where("updated_at >= ?", *since)
+	}
+}
+
+// 期間内に売れた商品 (from以上to未満)
+// プラットフォーム外で売れた場合は記録した日時、それ以外は最後の更新を売れた日時とみなす
+func SoldBetween(from time.Time, to time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("sold_out AND COALESCE(closed_at, updated_at) >= ? AND COALESCE(closed_at, updated_at) < ?", from, to)
+	}
+}
+
+// 作成日時の新しい順 (同時刻はIDの大きい順)
+func Newest() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC").Order("id DESC")
+	}
+}
+
+// 件数の上限 (0以下は制限しない)
+func Limit(limit int) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if limit <= 0 {
+			return db
+		}
+		return db.Limit(limit)
+	}
+}
+
+// ページ番号は1から数える
+type Page struct {
+	Number int
+	Size   int
+}
+
+func Paginate(page Page) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		number := max(page.Number, 1)
+		size := min(max(page.Size, 1), MaxPageSize)
+		return db.Offset((number - 1) * size).Limit(size)
+	}
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"errors"
+	"gin-fleamarket/models"
+
+	"gorm.io/gorm"
+)
+
+// 参照元ごとのアクセス数
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Clicks   uint   `json:"clicks"`
+}
+
+type IShortLinkRepository interface {
+	FindByCode(code string) (*models.ShortLink, error)
+	FindByItemId(itemId uint) (*models.ShortLink, error)
+	Create(newShortLink models.ShortLink) (*models.ShortLink, error)
+	RecordClick(shortLink models.ShortLink, referrer string) error
+	CountByReferrer(shortLinkId uint) ([]ReferrerCount, error)
+}
+
+type ShortLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewShortLinkRepository(db *gorm.DB) IShortLinkRepository {
+	return &ShortLinkRepository{db: db}
+}
+
+// FindByCode implements IShortLinkRepository.
+func (r *ShortLinkRepository) FindByCode(code string) (*models.ShortLink, error) {
+	var shortLink models.ShortLink
+	result := r.db.Where("code = ?", code).First(&shortLink)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Short link not found")
+		}
+		return nil, result.Error
+	}
+	return &shortLink, nil
+}
+
+// FindByItemId implements IShortLinkRepository.
+func (r *ShortLinkRepository) FindByItemId(itemId uint) (*models.ShortLink, error) {
+	var shortLink models.ShortLink
+	result := r.db.Where("item_id = ?", itemId).First(&shortLink)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			return nil, errors.New("Short link not found")
+		}
+		return nil, result.Error
+	}
+	return &shortLink, nil
+}
+
+// Create implements IShortLinkRepository.
+func (r *ShortLinkRepository) Create(newShortLink models.ShortLink) (*models.ShortLink, error) {
+	result := r.db.Create(&newShortLink)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newShortLink, nil
+}
+
+// RecordClick implements IShortLinkRepository.
+// 同時にアクセスされても数え漏れがないように、カウンターはSQLで加算する
+func (r *ShortLinkRepository) RecordClick(shortLink models.ShortLink, referrer string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&shortLink).UpdateColumn("clicks", gorm.Expr("clicks + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Create(&models.ShortLinkClick{ShortLinkID: shortLink.ID, Referrer: referrer}).Error
+	})
+}
+
+// CountByReferrer implements IShortLinkRepository.
+func (r *ShortLinkRepository) CountByReferrer(shortLinkId uint) ([]ReferrerCount, error) {
+	var counts []ReferrerCount
+	result := r.db.Model(&models.ShortLinkClick{}).
+		Select("referrer, COUNT(*) AS clicks").
+		Where("short_link_id = ?", shortLinkId).
+		Group("referrer").
+		Order("clicks DESC").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return counts, nil
+}
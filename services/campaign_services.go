@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"slices"
+)
+
+type ICampaignService interface {
+	FindActive() (*[]models.Campaign, error)
+	Create(createCampaignInput dto.CreateCampaignInput) (*models.Campaign, error)
+	Apply(items []models.Item) error
+}
+
+type CampaignService struct {
+	repository      repositories.ICampaignRepository
+	categoryService ICategoryService
+	clock           infra.IClock
+}
+
+func NewCampaignService(repository repositories.ICampaignRepository, categoryService ICategoryService, clock infra.IClock) ICampaignService {
+	return &CampaignService{repository: repository, categoryService: categoryService, clock: clock}
+}
+
+func (s *CampaignService) FindActive() (*[]models.Campaign, error) {
+	return s.repository.FindActive(s.clock.Now())
+}
+
+func (s *CampaignService) Create(createCampaignInput dto.CreateCampaignInput) (*models.Campaign, error) {
+	// 割引率と割引額はどちらか一方だけを指定する
+	if (createCampaignInput.DiscountPercent == 0) == (createCampaignInput.DiscountAmount == 0) {
+		return nil, errors.New("Invalid discount")
+	}
+	if createCampaignInput.CategoryID != nil {
+		if _, err := s.categoryService.FindById(*createCampaignInput.CategoryID); err != nil {
+			if err.Error() == "Category not found" {
+				return nil, errors.New("Invalid category")
+			}
+			return nil, err
+		}
+	}
+	newCampaign := models.Campaign{
+		Name:            createCampaignInput.Name,
+		StartsAt:        createCampaignInput.StartsAt,
+		EndsAt:          createCampaignInput.EndsAt,
+		CategoryID:      createCampaignInput.CategoryID,
+		DiscountPercent: createCampaignInput.DiscountPercent,
+		DiscountAmount:  createCampaignInput.DiscountAmount,
+	}
+	return s.repository.Create(newCampaign)
+}
+
+// 開催中のキャンペーンのうち、最も安くなるものを商品の表示価格に反映する
+func (s *CampaignService) Apply(items []models.Item) error {
+	for i := range items {
+		items[i].DiscountedPrice = nil
+		items[i].CampaignID = nil
+	}
+	campaigns, err := s.FindActive()
+	if err != nil {
+		return err
+	}
+	if len(*campaigns) == 0 {
+		return nil
+	}
+	// キャンペーンごとに対象のカテゴリIDを求めておく (nilは全ての商品が対象)
+	scopes := make([][]uint, len(*campaigns))
+	for i, v := range *campaigns {
+		if v.CategoryID == nil {
+			continue
+		}
+		categoryIds, err := s.categoryService.DescendantIds(*v.CategoryID)
+		if err != nil && err.Error() != "Category not found" {
+			return err
+		}
+		scopes[i] = append([]uint{}, categoryIds...)
+	}
+
+	for i := range items {
+		for j, campaign := range *campaigns {
+			if campaign.CategoryID != nil && (items[i].CategoryID == nil || !slices.Contains(scopes[j], *items[i].CategoryID)) {
+				continue
+			}
+			price := campaign.Apply(items[i].Price)
+			if items[i].DiscountedPrice == nil || price < *items[i].DiscountedPrice {
+				campaignId := campaign.ID
+				items[i].DiscountedPrice = &price
+				items[i].CampaignID = &campaignId
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/testutil/factory"
+	"testing"
+	"time"
+)
+
+type fakeCampaignRepository struct {
+	campaigns []models.Campaign
+}
+
+func (r *fakeCampaignRepository) FindActive(at time.Time) (*[]models.Campaign, error) {
+	return &r.campaigns, nil
+}
+
+func (r *fakeCampaignRepository) Create(newCampaign models.Campaign) (*models.Campaign, error) {
+	r.campaigns = append(r.campaigns, newCampaign)
+	return &newCampaign, nil
+}
+
+// 割合の割引はmodels.PercentとDiscountRoundingで計算した額と一致する
+func TestCampaignApplyUsesPercent(t *testing.T) {
+	prices := []uint{models.MinItemPrice, 9, 10, 15, 25, 35, 99, 101, 1250, 4999, 12345, models.MaxItemPrice}
+	for _, percent := range []uint{1, 5, 10, 15, 33, 50, 99, 100} {
+		campaign := models.Campaign{DiscountPercent: percent}
+		campaign.ID = uint(percent)
+		service := NewCampaignService(&fakeCampaignRepository{campaigns: []models.Campaign{campaign}}, nil, infra.NewFakeClock(time.Now()))
+
+		items := make([]models.Item, len(prices))
+		for i, price := range prices {
+			items[i] = factory.BuildItem(func(item *models.Item) { item.Price = price })
+		}
+		if err := service.Apply(items); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		for _, item := range items {
+			want := item.Price - models.Percent(item.Price, percent, models.DiscountRounding)
+			if item.DiscountedPrice == nil || *item.DiscountedPrice != want {
+				t.Errorf("%d%% off %d: DiscountedPrice = %v, want %d", percent, item.Price, item.DiscountedPrice, want)
+			}
+			if item.CampaignID == nil || *item.CampaignID != campaign.ID {
+				t.Errorf("%d%% off %d: CampaignID = %v, want %d", percent, item.Price, item.CampaignID, campaign.ID)
+			}
+		}
+	}
+}
+
+// 割引額は切り捨てるため、表示価格は切り上げた側になる
+func TestCampaignApplyRoundsDiscountDown(t *testing.T) {
+	campaign := models.Campaign{DiscountPercent: 10}
+	service := NewCampaignService(&fakeCampaignRepository{campaigns: []models.Campaign{campaign}}, nil, infra.NewFakeClock(time.Now()))
+	tests := []struct {
+		price uint
+		want  uint
+	}{
+		{price: 25, want: 23},
+		{price: 35, want: 32},
+		{price: 9, want: 9},
+		{price: 1000, want: 900},
+	}
+	for _, tt := range tests {
+		items := []models.Item{factory.BuildItem(func(item *models.Item) { item.Price = tt.price })}
+		if err := service.Apply(items); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if got := *items[0].DiscountedPrice; got != tt.want {
+			t.Errorf("10%% off %d = %d, want %d", tt.price, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ICategoryService interface {
+	FindAll() (*[]models.Category, error)
+	FindById(categoryId uint) (*models.Category, error)
+	Create(createCategoryInput dto.CreateCategoryInput) (*models.Category, error)
+	Move(categoryId uint, moveCategoryInput dto.MoveCategoryInput) (*models.Category, error)
+	Breadcrumb(categoryId uint) ([]models.Category, error)
+	DescendantIds(categoryId uint) ([]uint, error)
+	UpdateAttributeSchema(categoryId uint, updateAttributeSchemaInput dto.UpdateAttributeSchemaInput) (*models.Category, error)
+	AttributeSchema(categoryId uint) (models.AttributeSchema, error)
+	ValidateAttributes(categoryId *uint, attributes models.JSONMap) error
+	UpdateTranslations(categoryId uint, updateTranslationsInput dto.UpdateTranslationsInput) (*models.Category, error)
+	Merge(categoryId uint, mergeCategoryInput dto.MergeCategoryInput) (*models.Category, error)
+	RebuildItemCounts() error
+}
+
+type CategoryService struct {
+	repository repositories.ICategoryRepository
+}
+
+func NewCategoryService(repository repositories.ICategoryRepository) ICategoryService {
+	return &CategoryService{repository: repository}
+}
+
+// 子孫カテゴリも含めた商品数を付けて、ツリーの順に返す
+func (s *CategoryService) FindAll() (*[]models.Category, error) {
+	all, err := s.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	ordered := treeOrder(*all)
+	categories := &ordered
+	counts, err := s.repository.FindItemCounts()
+	if err != nil {
+		return nil, err
+	}
+	// パスが前方一致するカテゴリが子孫になる
+	for i := range *categories {
+		var total int64
+		for _, v := range *categories {
+			if strings.HasPrefix(v.Path, (*categories)[i].Path) {
+				total += counts[v.ID]
+			}
+		}
+		(*categories)[i].ItemCount = &total
+	}
+	return categories, nil
+}
+
+// 親の直後にその子孫が並ぶように並べる (兄弟は位置の順、同じ位置はIDの順)
+// パスは文字列として比べると "/10/" が "/2/" より前になるため、並べ替えには使わない
+func treeOrder(categories []models.Category) []models.Category {
+	sorted := slices.Clone(categories)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position != sorted[j].Position {
+			return sorted[i].Position < sorted[j].Position
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	// ルートはIDを0として扱う
+	children := map[uint][]models.Category{}
+	ids := map[uint]bool{}
+	for _, v := range sorted {
+		ids[v.ID] = true
+	}
+	for _, v := range sorted {
+		var parentId uint
+		if v.ParentID != nil && ids[*v.ParentID] {
+			parentId = *v.ParentID
+		}
+		children[parentId] = append(children[parentId], v)
+	}
+	ordered := make([]models.Category, 0, len(categories))
+	var visit func(parentId uint)
+	visit = func(parentId uint) {
+		for _, v := range children[parentId] {
+			ordered = append(ordered, v)
+			visit(v.ID)
+		}
+	}
+	visit(0)
+	return ordered
+}
+
+func (s *CategoryService) RebuildItemCounts() error {
+	return s.repository.RebuildItemCounts()
+}
+
+func (s *CategoryService) FindById(categoryId uint) (*models.Category, error) {
+	return s.repository.FindById(categoryId)
+}
+
+func (s *CategoryService) Create(createCategoryInput dto.CreateCategoryInput) (*models.Category, error) {
+	parent, err := s.findParent(createCategoryInput.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	newCategory := models.Category{
+		Name:     createCategoryInput.Name,
+		ParentID: createCategoryInput.ParentID,
+		Position: createCategoryInput.Position,
+	}
+	return s.repository.Create(newCategory, parent)
+}
+
+func (s *CategoryService) Move(categoryId uint, moveCategoryInput dto.MoveCategoryInput) (*models.Category, error) {
+	targetCategory, err := s.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.findParent(moveCategoryInput.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	// 自身や子孫の下には移動できない
+	if parent != nil && strings.HasPrefix(parent.Path, targetCategory.Path) {
+		return nil, errors.New("Invalid parent category")
+	}
+	if moveCategoryInput.Position != nil {
+		targetCategory.Position = *moveCategoryInput.Position
+	}
+	return s.repository.Move(*targetCategory, parent)
+}
+
+// 重複したカテゴリを統合先にまとめ、統合先を返す
+func (s *CategoryService) Merge(categoryId uint, mergeCategoryInput dto.MergeCategoryInput) (*models.Category, error) {
+	source, err := s.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.FindById(mergeCategoryInput.TargetID)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			return nil, errors.New("Invalid target category")
+		}
+		return nil, err
+	}
+	// 自身や子孫には統合できない
+	if strings.HasPrefix(target.Path, source.Path) {
+		return nil, errors.New("Invalid target category")
+	}
+	if err := s.repository.Merge(*source, *target); err != nil {
+		return nil, err
+	}
+	return s.FindById(target.ID)
+}
+
+// ルートから指定カテゴリまでのカテゴリを順に返す
+func (s *CategoryService) Breadcrumb(categoryId uint) ([]models.Category, error) {
+	category, err := s.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint{}
+	for _, v := range strings.Split(strings.Trim(category.Path, "/"), "/") {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	categories, err := s.repository.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	breadcrumb := *categories
+	sort.Slice(breadcrumb, func(i, j int) bool {
+		return len(breadcrumb[i].Path) < len(breadcrumb[j].Path)
+	})
+	return breadcrumb, nil
+}
+
+// 自身を含む子孫カテゴリのIDを返す
+// 統合されたカテゴリのIDの場合は、統合先のカテゴリで絞り込む
+func (s *CategoryService) DescendantIds(categoryId uint) ([]uint, error) {
+	category, err := s.FindById(categoryId)
+	if err != nil && err.Error() == "Category not found" {
+		aliasOf, aliasErr := s.repository.FindAlias(categoryId)
+		if aliasErr != nil {
+			return nil, aliasErr
+		}
+		category, err = s.FindById(aliasOf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	descendants, err := s.repository.FindDescendants(*category)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint{}
+	for _, v := range *descendants {
+		ids = append(ids, v.ID)
+	}
+	return ids, nil
+}
+
+func (s *CategoryService) UpdateAttributeSchema(categoryId uint, updateAttributeSchemaInput dto.UpdateAttributeSchemaInput) (*models.Category, error) {
+	targetCategory, err := s.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	schema := models.AttributeSchema{}
+	keys := map[string]bool{}
+	for _, v := range updateAttributeSchemaInput.Attributes {
+		if keys[v.Key] {
+			return nil, errors.New("Duplicate attribute key")
+		}
+		keys[v.Key] = true
+		schema = append(schema, models.AttributeDefinition{
+			Key:      v.Key,
+			Type:     v.Type,
+			Required: v.Required,
+			Options:  v.Options,
+		})
+	}
+	targetCategory.AttributeSchema = schema
+	return s.repository.Update(*targetCategory)
+}
+
+// 翻訳はまとめて置き換える (含めなかった言語は日本語の名前に戻る)
+func (s *CategoryService) UpdateTranslations(categoryId uint, updateTranslationsInput dto.UpdateTranslationsInput) (*models.Category, error) {
+	targetCategory, err := s.FindById(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	translations := models.LocalizedNames{}
+	for language, name := range updateTranslationsInput.Translations {
+		if !slices.Contains(models.TranslationLanguages, language) {
+			return nil, errors.New("Invalid translation language")
+		}
+		translations[language] = name
+	}
+	targetCategory.Translations = translations
+	return s.repository.Update(*targetCategory)
+}
+
+// 親カテゴリの定義を引き継いだ属性定義を返す (同じキーは子の定義が優先)
+func (s *CategoryService) AttributeSchema(categoryId uint) (models.AttributeSchema, error) {
+	breadcrumb, err := s.Breadcrumb(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	schema := models.AttributeSchema{}
+	index := map[string]int{}
+	for _, category := range breadcrumb {
+		for _, v := range category.AttributeSchema {
+			if i, ok := index[v.Key]; ok {
+				schema[i] = v
+				continue
+			}
+			index[v.Key] = len(schema)
+			schema = append(schema, v)
+		}
+	}
+	return schema, nil
+}
+
+func (s *CategoryService) ValidateAttributes(categoryId *uint, attributes models.JSONMap) error {
+	schema := models.AttributeSchema{}
+	if categoryId != nil {
+		var err error
+		schema, err = s.AttributeSchema(*categoryId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !matchSchema(schema, attributes) {
+		return errors.New("Invalid attributes")
+	}
+	return nil
+}
+
+// 定義にないキーや型の合わない値がなく、必須の値が揃っているか
+func matchSchema(schema models.AttributeSchema, values models.JSONMap) bool {
+	definitions := map[string]models.AttributeDefinition{}
+	for _, v := range schema {
+		definitions[v.Key] = v
+		if _, ok := values[v.Key]; v.Required && !ok {
+			return false
+		}
+	}
+	for key, value := range values {
+		definition, ok := definitions[key]
+		if !ok || !validAttributeValue(definition, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func validAttributeValue(definition models.AttributeDefinition, value interface{}) bool {
+	switch definition.Type {
+	case models.AttributeTypeString:
+		_, ok := value.(string)
+		return ok
+	case models.AttributeTypeNumber:
+		_, ok := value.(float64)
+		return ok
+	case models.AttributeTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case models.AttributeTypeEnum:
+		v, ok := value.(string)
+		return ok && slices.Contains(definition.Options, v)
+	}
+	return false
+}
+
+func (s *CategoryService) findParent(parentId *uint) (*models.Category, error) {
+	if parentId == nil {
+		return nil, nil
+	}
+	parent, err := s.FindById(*parentId)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			return nil, errors.New("Invalid parent category")
+		}
+		return nil, err
+	}
+	return parent, nil
+}
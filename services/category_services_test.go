@@ -0,0 +1,59 @@
+package services
+
+import (
+	"gin-fleamarket/models"
+	"gin-fleamarket/testutil/factory"
+	"slices"
+	"testing"
+)
+
+func treeCategory(id uint, parentId uint, position int) models.Category {
+	return factory.BuildCategory(func(c *models.Category) {
+		c.ID = id
+		c.Position = position
+		if parentId != 0 {
+			c.ParentID = &parentId
+		}
+	})
+}
+
+func TestTreeOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []models.Category
+		want       []uint
+	}{
+		{
+			name:       "children follow their parent",
+			categories: []models.Category{treeCategory(1, 0, 0), treeCategory(2, 0, 1), treeCategory(3, 1, 0), treeCategory(4, 2, 0)},
+			want:       []uint{1, 3, 2, 4},
+		},
+		{
+			name:       "siblings are ordered by position",
+			categories: []models.Category{treeCategory(1, 0, 0), treeCategory(2, 1, 2), treeCategory(3, 1, 0), treeCategory(4, 1, 1)},
+			want:       []uint{1, 3, 4, 2},
+		},
+		{
+			name:       "ties on position are ordered by id",
+			categories: []models.Category{treeCategory(5, 0, 0), treeCategory(3, 0, 0), treeCategory(4, 0, 0)},
+			want:       []uint{3, 4, 5},
+		},
+		{
+			// パスを文字列で比べると "/10/" が "/2/" より前になる
+			name:       "ids with more digits are not ordered as text",
+			categories: []models.Category{treeCategory(10, 0, 0), treeCategory(2, 0, 0)},
+			want:       []uint{2, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []uint{}
+			for _, v := range treeOrder(tt.categories) {
+				got = append(got, v.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("treeOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 集計できる期間 (dto.FeatureUsageInputのバリデーションと揃える)
+var featureUsagePeriods = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+}
+
+type IFeatureUsageService interface {
+	// リクエストごとに呼ばれるため、DBには書き込まずに数えておく (featureは空にできる)
+	Record(endpoint string, feature string)
+	// 数えておいた回数を書き込む
+	Flush() error
+	FindUsage(featureUsageInput dto.FeatureUsageInput) (*dto.FeatureUsageReport, error)
+}
+
+type usageKey struct {
+	kind        string
+	name        string
+	bucketStart time.Time
+}
+
+type FeatureUsageService struct {
+	repository repositories.IFeatureUsageRepository
+	clock      infra.IClock
+	mu         sync.Mutex
+	pending    map[usageKey]int64
+}
+
+func NewFeatureUsageService(repository repositories.IFeatureUsageRepository, clock infra.IClock) IFeatureUsageService {
+	return &FeatureUsageService{repository: repository, clock: clock, pending: map[usageKey]int64{}}
+}
+
+func (s *FeatureUsageService) Record(endpoint string, feature string) {
+	bucketStart := s.clock.Now().Truncate(models.FeatureUsageBucket)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[usageKey{kind: models.FeatureUsageKindEndpoint, name: endpoint, bucketStart: bucketStart}]++
+	if feature != "" {
+		s.pending[usageKey{kind: models.FeatureUsageKindFeature, name: feature, bucketStart: bucketStart}]++
+	}
+}
+
+// 書き込めなかった回数は次に書き込むときまで持ち越す
+func (s *FeatureUsageService) Flush() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[usageKey]int64{}
+	s.mu.Unlock()
+
+	usages := make([]models.FeatureUsage, 0, len(pending))
+	for k, count := range pending {
+		usages = append(usages, models.FeatureUsage{Kind: k.kind, Name: k.name, BucketStart: k.bucketStart, Count: count})
+	}
+	if err := s.repository.Add(usages); err != nil {
+		s.mu.Lock()
+		for k, count := range pending {
+			s.pending[k] += count
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *FeatureUsageService) FindUsage(featureUsageInput dto.FeatureUsageInput) (*dto.FeatureUsageReport, error) {
+	to := s.clock.Now()
+	from := to.Add(-featureUsagePeriods[featureUsageInput.Period])
+	// 期間の始まりを含む区切りから数える
+	usages, err := s.repository.FindBetween(from.Truncate(models.FeatureUsageBucket), to)
+	if err != nil {
+		return nil, err
+	}
+
+	series := map[string]map[string]*dto.UsageSeries{
+		models.FeatureUsageKindEndpoint: {},
+		models.FeatureUsageKindFeature:  {},
+	}
+	for _, v := range *usages {
+		byName, ok := series[v.Kind]
+		if !ok {
+			continue
+		}
+		usage, ok := byName[v.Name]
+		if !ok {
+			usage = &dto.UsageSeries{Name: v.Name, Buckets: []dto.UsageBucket{}}
+			byName[v.Name] = usage
+		}
+		start := usageBucketStart(v.BucketStart.In(to.Location()), featureUsageInput.Bucket)
+		usage.Total += v.Count
+		// 行は区切りの古い順に並んでいるため、同じ区切りは続けて現れる
+		if n := len(usage.Buckets); n > 0 && usage.Buckets[n-1].Start.Equal(start) {
+			usage.Buckets[n-1].Count += v.Count
+			continue
+		}
+		usage.Buckets = append(usage.Buckets, dto.UsageBucket{Start: start, Count: v.Count})
+	}
+	return &dto.FeatureUsageReport{
+		Period:    featureUsageInput.Period,
+		Bucket:    featureUsageInput.Bucket,
+		From:      from,
+		To:        to,
+		Endpoints: sortedUsageSeries(series[models.FeatureUsageKindEndpoint]),
+		Features:  sortedUsageSeries(series[models.FeatureUsageKindFeature]),
+	}, nil
+}
+
+func usageBucketStart(t time.Time, bucket string) time.Time {
+	if bucket == dto.UsageBucketDay {
+		year, month, day := t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+// 利用回数の多い順 (同じ回数は名前の順)
+func sortedUsageSeries(byName map[string]*dto.UsageSeries) []dto.UsageSeries {
+	series := make([]dto.UsageSeries, 0, len(byName))
+	for _, v := range byName {
+		series = append(series, *v)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Total != series[j].Total {
+			return series[i].Total > series[j].Total
+		}
+		return series[i].Name < series[j].Name
+	})
+	return series
+}
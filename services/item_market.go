@@ -0,0 +1,67 @@
+package services
+
+import (
+	"gin-fleamarket/dto"
+	"gin-fleamarket/repositories"
+	"math"
+	"time"
+)
+
+// 集計できる期間 (dto.SoldStatsInputのバリデーションと揃える)
+var soldStatsPeriods = map[string]time.Duration{
+	"7d":   7 * 24 * time.Hour,
+	"30d":  30 * 24 * time.Hour,
+	"90d":  90 * 24 * time.Hour,
+	"365d": 365 * 24 * time.Hour,
+}
+
+// 中央値の変化率がこの範囲内なら横ばいとみなす
+const flatTrendThreshold = 0.05
+
+// 期間内に売れた商品の件数と価格を、直前の同じ長さの期間と比べて返す
+// 個々の商品を特定できないよう、件数が少ない場合は価格を返さない
+func (s *ItemService) SoldStats(soldStatsInput dto.SoldStatsInput) (*dto.SoldStats, error) {
+	categoryIds, err := s.descendantCategoryIds(soldStatsInput.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	query := repositories.ItemQuery{CategoryIds: categoryIds}
+	period := soldStatsPeriods[soldStatsInput.Period]
+	to := s.clock.Now()
+	from := to.Add(-period)
+	current, err := s.repository.SoldPriceStats(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repository.SoldPriceStats(query, from.Add(-period), from)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &dto.SoldStats{Period: soldStatsInput.Period, From: from, To: to, Count: current.Count}
+	if previous.Count > 0 {
+		countChange := roundRate(float64(current.Count-previous.Count) / float64(previous.Count))
+		stats.CountChange = &countChange
+	}
+	if current.Count < minComparables {
+		return stats, nil
+	}
+	stats.MedianPrice = roundPrice(current.Median)
+	if previous.Count >= minComparables {
+		medianChange := roundRate((current.Median - previous.Median) / previous.Median)
+		stats.MedianPriceChange = &medianChange
+		trend := dto.TrendFlat
+		if medianChange > flatTrendThreshold {
+			trend = dto.TrendUp
+		} else if medianChange < -flatTrendThreshold {
+			trend = dto.TrendDown
+		}
+		stats.Trend = &trend
+	}
+	return stats, nil
+}
+
+// 変化率は小数第3位までにする
+func roundRate(rate float64) float64 {
+	return math.Round(rate*1000) / 1000
+}
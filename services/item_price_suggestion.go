@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/repositories"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 価格の提案に使う、売れた商品の期間
+const priceSuggestionWindow = 90 * 24 * time.Hour
+
+// 比較できる商品がこれより少ない場合は価格を提案しない
+const minComparables = 3
+
+// 価格の提案は日付が変わるまで使い回し、翌日の最初のリクエストで集計し直す
+// (売れた商品の分布は1日の中ではほとんど変わらないため)
+type priceSuggestionCache struct {
+	mu      sync.Mutex
+	expires time.Time
+	entries map[string]dto.PriceSuggestion
+}
+
+func (c *priceSuggestionCache) get(key string, now time.Time) (dto.PriceSuggestion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.Before(c.expires) {
+		year, month, day := now.Date()
+		c.expires = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+		c.entries = map[string]dto.PriceSuggestion{}
+	}
+	suggestion, ok := c.entries[key]
+	return suggestion, ok
+}
+
+func (c *priceSuggestionCache) set(key string, suggestion dto.PriceSuggestion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = suggestion
+}
+
+// 名前が似ていて (全ての語を含み)、同じカテゴリ (子孫を含む) で最近売れた商品の価格から提案する
+func (s *ItemService) SuggestPrice(priceSuggestionInput dto.PriceSuggestionInput) (*dto.PriceSuggestion, error) {
+	terms := strings.Fields(strings.ToLower(priceSuggestionInput.Name))
+	if len(terms) == 0 {
+		return nil, errors.New("Invalid name")
+	}
+	categoryIds, err := s.descendantCategoryIds(priceSuggestionInput.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(terms, " ")
+	if priceSuggestionInput.CategoryID != nil {
+		key += "\x00" + strconv.FormatUint(uint64(*priceSuggestionInput.CategoryID), 10)
+	}
+	now := s.clock.Now()
+	if suggestion, ok := s.priceSuggestions.get(key, now); ok {
+		return &suggestion, nil
+	}
+
+	since := now.Add(-priceSuggestionWindow)
+	stats, err := s.repository.SoldPriceStats(repositories.ItemQuery{CategoryIds: categoryIds, Terms: terms}, since, now)
+	if err != nil {
+		return nil, err
+	}
+	suggestion := dto.PriceSuggestion{Comparables: stats.Count, Since: since}
+	if stats.Count >= minComparables {
+		suggestion.Low = roundPrice(stats.P25)
+		suggestion.Median = roundPrice(stats.Median)
+		suggestion.High = roundPrice(stats.P75)
+	}
+	s.priceSuggestions.set(key, suggestion)
+	return &suggestion, nil
+}
+
+func roundPrice(price float64) *uint {
+	rounded := uint(math.Round(price))
+	return &rounded
+}
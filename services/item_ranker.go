@@ -0,0 +1,87 @@
+package services
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// 商品一覧の並び順を決める
+// 新しい順はDBで並べるため、ここでは点数を付けて並べ替えるものを扱う
+type IItemRanker interface {
+	// itemsを並べ替える (評価が同じ商品は新しい順、同時刻はIDの大きい順)
+	Rank(items []models.Item)
+}
+
+// おすすめ順の重み (新しさと出品内容の充実度)
+const (
+	freshnessWeight   = 0.5
+	imageWeight       = 0.2
+	descriptionWeight = 0.1
+	attributesWeight  = 0.1
+	campaignWeight    = 0.1
+)
+
+// 新しさの点数が半分になるまでの期間
+const freshnessHalfLife = 7 * 24 * time.Hour
+
+// 説明文がこの文字数以上あれば充実しているとみなす
+const richDescriptionLength = 50
+
+// 出品内容が充実していて新しい商品を上に、売り切れた商品は最後に並べる
+type RecommendedRanker struct {
+	clock infra.IClock
+}
+
+func NewRecommendedRanker(clock infra.IClock) IItemRanker {
+	return &RecommendedRanker{clock: clock}
+}
+
+func (r *RecommendedRanker) Rank(items []models.Item) {
+	now := r.clock.Now()
+	scores := make(map[uint]float64, len(items))
+	for _, v := range items {
+		scores[v.ID] = r.score(v, now)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		return newer(a, b)
+	})
+}
+
+// 0〜1の点数 (売り切れた商品は負になる)
+// キャンペーンの割引はApplyの後に並べ替えた場合だけ加点される
+func (r *RecommendedRanker) score(item models.Item, now time.Time) float64 {
+	age := max(now.Sub(item.CreatedAt), 0)
+	score := freshnessWeight * math.Pow(0.5, float64(age)/float64(freshnessHalfLife))
+	if item.ImageHash != "" {
+		score += imageWeight
+	}
+	if utf8.RuneCountInString(item.Description) >= richDescriptionLength {
+		score += descriptionWeight
+	}
+	if len(item.Attributes) > 0 {
+		score += attributesWeight
+	}
+	if item.DiscountedPrice != nil {
+		score += campaignWeight
+	}
+	if item.SoldOut {
+		score -= 1
+	}
+	return score
+}
+
+// 作成日時の新しい順 (同時刻はIDの大きい順、scopes.Newestと同じ)
+func newer(a models.Item, b models.Item) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
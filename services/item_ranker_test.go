@@ -0,0 +1,141 @@
+package services
+
+import (
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/testutil/factory"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rankerNow = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func rankedItem(id uint, age time.Duration, overrides ...func(item *models.Item)) models.Item {
+	return factory.BuildItem(append([]func(item *models.Item){func(item *models.Item) {
+		item.ID = id
+		item.CreatedAt = rankerNow.Add(-age)
+	}}, overrides...)...)
+}
+
+func withImage(item *models.Item)   { item.ImageHash = "ffff0000ffff0000" }
+func withSoldOut(item *models.Item) { item.SoldOut = true }
+
+func TestRecommendedRankerScore(t *testing.T) {
+	discounted := uint(800)
+	week := 7 * 24 * time.Hour
+	tests := []struct {
+		name string
+		item models.Item
+		want float64
+	}{
+		{name: "new item without extras", item: rankedItem(1, 0), want: freshnessWeight},
+		{name: "freshness halves after the half-life", item: rankedItem(1, week), want: freshnessWeight / 2},
+		{name: "freshness keeps decaying", item: rankedItem(1, 2*week), want: freshnessWeight / 4},
+		{name: "future created at counts as new", item: rankedItem(1, -time.Hour), want: freshnessWeight},
+		{name: "image", item: rankedItem(1, 0, withImage), want: freshnessWeight + imageWeight},
+		{
+			name: "rich description",
+			item: rankedItem(1, 0, func(item *models.Item) {
+				item.Description = strings.Repeat("あ", richDescriptionLength)
+			}),
+			want: freshnessWeight + descriptionWeight,
+		},
+		{
+			name: "short description",
+			item: rankedItem(1, 0, func(item *models.Item) {
+				item.Description = strings.Repeat("あ", richDescriptionLength-1)
+			}),
+			want: freshnessWeight,
+		},
+		{
+			name: "attributes",
+			item: rankedItem(1, 0, func(item *models.Item) {
+				item.Attributes = models.JSONMap{"size": "M"}
+			}),
+			want: freshnessWeight + attributesWeight,
+		},
+		{
+			name: "campaign discount",
+			item: rankedItem(1, 0, func(item *models.Item) {
+				item.DiscountedPrice = &discounted
+			}),
+			want: freshnessWeight + campaignWeight,
+		},
+		{name: "sold out", item: rankedItem(1, 0, withImage, withSoldOut), want: freshnessWeight + imageWeight - 1},
+	}
+	ranker := &RecommendedRanker{clock: infra.NewFakeClock(rankerNow)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranker.score(tt.item, rankerNow); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendedRankerRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []models.Item
+		want  []uint
+	}{
+		{
+			name:  "higher score first",
+			items: []models.Item{rankedItem(1, 0), rankedItem(2, 0, withImage)},
+			want:  []uint{2, 1},
+		},
+		{
+			name:  "sold out items last even when newer and richer",
+			items: []models.Item{rankedItem(1, 0, withImage, withSoldOut), rankedItem(2, 60*24*time.Hour)},
+			want:  []uint{2, 1},
+		},
+		{
+			// 十分に古い商品は新しさの点数が0になり、点数が同じになる
+			name:  "ties are broken newest first",
+			items: []models.Item{rankedItem(2, 200*365*24*time.Hour), rankedItem(1, 100*365*24*time.Hour)},
+			want:  []uint{1, 2},
+		},
+		{
+			name:  "same created at is broken by larger id",
+			items: []models.Item{rankedItem(3, time.Hour), rankedItem(7, time.Hour), rankedItem(5, time.Hour)},
+			want:  []uint{7, 5, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranker := NewRecommendedRanker(infra.NewFakeClock(rankerNow))
+			// 入力の順に依存しないことも確認する
+			for _, items := range [][]models.Item{slices.Clone(tt.items), reversed(tt.items)} {
+				ranker.Rank(items)
+				got := []uint{}
+				for _, v := range items {
+					got = append(got, v.ID)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Rank() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	older := rankedItem(9, time.Hour)
+	newer1 := rankedItem(1, 0)
+	newer2 := rankedItem(2, 0)
+	if !newer(newer1, older) || newer(older, newer1) {
+		t.Error("newer created at should come first")
+	}
+	if !newer(newer2, newer1) || newer(newer1, newer2) {
+		t.Error("larger id should come first when created at is equal")
+	}
+}
+
+func reversed(items []models.Item) []models.Item {
+	r := slices.Clone(items)
+	slices.Reverse(r)
+	return r
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 検索結果の件数の上限
+const searchSize = 50
+
+// 検索結果がこの件数より少ない場合に、綴りを直した検索語を提案する
+const didYouMeanThreshold = 3
+
+// 入力補完で返す検索語と商品の件数
+const suggestSize = 5
+
+// 説明文の抜粋で、最初に一致した語の前後に残す文字数
+const snippetContext = 40
+
+// 検索結果 (ハイライトは要求された場合のみ、商品IDごとに持つ)
+type SearchResult struct {
+	Items      []models.Item
+	Highlights map[uint]dto.ItemHighlight
+	// 綴りを直すと結果が増える場合の検索語
+	DidYouMean *string
+	// 件数の上限に関係なく、一致した全ての商品で数える
+	Facets dto.SearchFacets
+}
+
+func (s *ItemService) Search(searchItemsInput dto.SearchItemsInput) (*SearchResult, error) {
+	terms := strings.Fields(searchItemsInput.Q)
+	if len(terms) == 0 {
+		return nil, errors.New("Invalid query")
+	}
+	categoryIds, err := s.descendantCategoryIds(searchItemsInput.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repository.FindAll(repositories.ItemQuery{CategoryIds: categoryIds, Terms: terms, Newest: true, Limit: searchSize})
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.repository.CountFacets(repositories.ItemQuery{CategoryIds: categoryIds, Terms: terms})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.campaignService.Apply(*items); err != nil {
+		return nil, err
+	}
+	result := &SearchResult{Items: *items, Facets: toSearchFacets(facets)}
+	if len(*items) < didYouMeanThreshold {
+		didYouMean, err := s.correctTerms(terms, categoryIds, len(*items))
+		if err != nil {
+			return nil, err
+		}
+		result.DidYouMean = didYouMean
+	}
+	if searchItemsInput.Highlight {
+		result.Highlights = map[uint]dto.ItemHighlight{}
+		for _, v := range *items {
+			result.Highlights[v.ID] = dto.ItemHighlight{
+				Name:        highlight(v.Name, terms),
+				Description: highlight(snippet(v.Description, terms), terms),
+			}
+		}
+	}
+	return result, nil
+}
+
+// 件数の多い順に並べる (価格帯は安い順)
+func toSearchFacets(facets *repositories.ItemFacets) dto.SearchFacets {
+	result := dto.SearchFacets{
+		Categories: make([]dto.CategoryFacet, 0, len(facets.Categories)),
+		Prices:     make([]dto.PriceFacet, 0, len(facets.Prices)),
+		Conditions: make([]dto.ConditionFacet, 0, len(facets.Conditions)),
+	}
+	for k, v := range facets.Categories {
+		result.Categories = append(result.Categories, dto.CategoryFacet{CategoryID: k, Count: v})
+	}
+	sort.Slice(result.Categories, func(i, j int) bool {
+		a, b := result.Categories[i], result.Categories[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.CategoryID < b.CategoryID)
+	})
+	for i, v := range facets.Prices {
+		facet := dto.PriceFacet{Count: v}
+		if i > 0 {
+			facet.Min = repositories.PriceFacetBounds[i-1]
+		}
+		if i < len(repositories.PriceFacetBounds) {
+			max := repositories.PriceFacetBounds[i]
+			facet.Max = &max
+		}
+		result.Prices = append(result.Prices, facet)
+	}
+	for k, v := range facets.Conditions {
+		result.Conditions = append(result.Conditions, dto.ConditionFacet{Condition: k, Count: v})
+	}
+	sort.Slice(result.Conditions, func(i, j int) bool {
+		a, b := result.Conditions[i], result.Conditions[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Condition < b.Condition)
+	})
+	return result
+}
+
+// 語ごとに商品名の中の似た語に置き換え、結果が増える場合だけ提案する
+func (s *ItemService) correctTerms(terms []string, categoryIds []uint, found int) (*string, error) {
+	corrected := make([]string, len(terms))
+	changed := false
+	for i, v := range terms {
+		corrected[i] = v
+		word, err := s.repository.FindSimilarTerm(v)
+		if err != nil {
+			return nil, err
+		}
+		if word != "" && word != strings.ToLower(v) {
+			corrected[i] = word
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	items, err := s.repository.FindAll(repositories.ItemQuery{CategoryIds: categoryIds, Terms: corrected, Limit: found + 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(*items) <= found {
+		return nil, nil
+	}
+	didYouMean := strings.Join(corrected, " ")
+	return &didYouMean, nil
+}
+
+// 入力中の検索語の補完候補と、名前が一致する商品を返す
+// 最後の語だけを補完し、それより前の語はそのまま付ける
+func (s *ItemService) Suggest(q string) ([]string, *[]models.Item, error) {
+	terms := strings.Fields(q)
+	if len(terms) == 0 {
+		return nil, nil, errors.New("Invalid query")
+	}
+	last := terms[len(terms)-1]
+	words, err := s.repository.SuggestTerms(terms[:len(terms)-1], last, suggestSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	head := strings.Join(terms[:len(terms)-1], " ")
+	completions := make([]string, 0, len(words))
+	for _, v := range words {
+		completions = append(completions, strings.TrimSpace(head+" "+v))
+	}
+	items, err := s.repository.SuggestTitles(strings.Join(terms, " "), suggestSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return completions, items, nil
+}
+
+// 一致した語を<mark>で囲む (それ以外の部分はHTMLとしてエスケープする)
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	// 大文字小文字の変換で長さが変わる文字を含む場合は位置がずれるため、囲まない
+	if len(lower) != len(text) {
+		return html.EscapeString(text)
+	}
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for offset := 0; ; {
+			i := strings.Index(lower[offset:], term)
+			if i < 0 {
+				break
+			}
+			for j := offset + i; j < offset+i+len(term); j++ {
+				marked[j] = true
+			}
+			offset += i + len(term)
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// 最初に一致した語の前後だけを切り出す
+func snippet(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	first := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, strings.ToLower(term)); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	if first < 0 || len(lower) != len(text) {
+		first = 0
+	}
+	runes := []rune(text)
+	center := utf8.RuneCountInString(text[:first])
+	start := max(center-snippetContext, 0)
+	end := min(center+snippetContext, len(runes))
+	result := string(runes[start:end])
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(runes) {
+		result += "…"
+	}
+	return result
+}
@@ -1,50 +1,288 @@
 package services
 
 import (
+	"errors"
 	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
 	"gin-fleamarket/models"
 	"gin-fleamarket/repositories"
+	"gin-fleamarket/repositories/scopes"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// ページ分けで件数を省略した場合の1ページの件数
+const defaultPageSize = 20
+
+// 類似画像とみなすハッシュ距離の上限
+const similarImageDistance = 10
+
+// ストリーミング時に1回で読み込む件数
+const streamBatchSize = 500
+
+// 点数を付けて並べ替える候補の件数 (新着順にこの件数までを対象にする)
+const rankedCandidateLimit = 1000
+
+// metadataに保存できるキーと型
+var metadataSchema = models.AttributeSchema{
+	{Key: "brand", Type: models.AttributeTypeString},
+	{Key: "color", Type: models.AttributeTypeString},
+	{Key: "condition", Type: models.AttributeTypeEnum, Options: []string{"new", "like_new", "good", "fair", "poor"}},
+	{Key: "externalId", Type: models.AttributeTypeString},
+	{Key: "shippingDays", Type: models.AttributeTypeNumber},
+	{Key: "negotiable", Type: models.AttributeTypeBoolean},
+}
+
 type IItemService interface {
-	FindAll() (*[]models.Item, error)
+	FindAll(findItemsInput dto.FindItemsInput) (*ItemList, error)
+	FindRecent(categoryId *uint, limit int) (*[]models.Item, error)
 	FindById(itemId uint) (*models.Item, error)
 	Create(createItemInput dto.CreateItemInput) (*models.Item, error)
-	Update(itemId uint, updateItemInput dto.UpdateItemInput) (*models.Item, error)
-	Delete(itemId uint) error
+	Update(itemId uint, updateItemInput dto.UpdateItemInput, precondition ItemPrecondition) (*models.Item, error)
+	Patch(itemId uint, updateItemInput dto.UpdateItemInput, precondition ItemPrecondition) (*models.Item, error)
+	Delete(itemId uint, precondition ItemPrecondition) error
+	MarkSoldExternally(itemId uint, precondition ItemPrecondition) (*models.Item, error)
+	SetLegalHold(itemId uint, legalHold bool) (*models.Item, error)
+	SetImage(itemId uint, image io.Reader, altText string) (*models.Item, error)
+	Search(searchItemsInput dto.SearchItemsInput) (*SearchResult, error)
+	Suggest(q string) ([]string, *[]models.Item, error)
+	SuggestPrice(priceSuggestionInput dto.PriceSuggestionInput) (*dto.PriceSuggestion, error)
+	SoldStats(soldStatsInput dto.SoldStatsInput) (*dto.SoldStats, error)
+	SearchByImage(image io.Reader) (*[]models.Item, error)
+	Stream(streamItemsInput dto.StreamItemsInput, fn func(items []models.Item) error) error
 }
 
+// 書き込む前の商品が満たすべき条件 (If-Matchなど)。nilの場合は確認しない
+// 条件を確認してから書き込むまでに他の更新が入った場合も"Precondition failed"になる
+type ItemPrecondition func(item *models.Item) bool
+
 type ItemService struct {
-	repository repositories.IItemRepository
+	repository      repositories.IItemRepository
+	categoryService ICategoryService
+	campaignService ICampaignService
+	vision          infra.IVisionProvider
+	clock           infra.IClock
+	// 日付が変わるまで使い回す価格の提案
+	priceSuggestions *priceSuggestionCache
+	// 新しい順以外の並び順 (?sort= の値ごと)
+	rankers map[string]IItemRanker
 }
 
-func NewItemService(repository repositories.IItemRepository) IItemService {
-	return &ItemService{repository: repository}
+func NewItemService(repository repositories.IItemRepository, categoryService ICategoryService, campaignService ICampaignService, vision infra.IVisionProvider, clock infra.IClock) IItemService {
+	return &ItemService{repository: repository, categoryService: categoryService, campaignService: campaignService, vision: vision, clock: clock, priceSuggestions: &priceSuggestionCache{}, rankers: map[string]IItemRanker{
+		dto.SortRecommended: NewRecommendedRanker(clock),
+	}}
 }
 
-func (s *ItemService) FindAll() (*[]models.Item, error) {
-	return s.repository.FindAll()
+// 商品の一覧 (全件をメモリに載せないよう、商品はEachでバッチごとに読み込む)
+type ItemList struct {
+	// ページ分けしない場合はnil
+	Meta *dto.PaginationMeta
+	each func(fn func(items []models.Item) error) error
+}
+
+// 商品をバッチごとにfnへ渡す (キャンペーンは適用済み)
+func (l *ItemList) Each(fn func(items []models.Item) error) error {
+	return l.each(fn)
+}
+
+// 条件の誤りはEachより前にここで返す
+func (s *ItemService) FindAll(findItemsInput dto.FindItemsInput) (*ItemList, error) {
+	query := repositories.ItemQuery{Attributes: findItemsInput.Attributes}
+	if len(findItemsInput.Metadata) > 0 {
+		metadata, err := parseMetadataFilter(findItemsInput.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		query.Metadata = metadata
+	}
+	categoryIds, err := s.descendantCategoryIds(findItemsInput.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	query.CategoryIds = categoryIds
+	var page *scopes.Page
+	if findItemsInput.Page > 0 || findItemsInput.Limit > 0 {
+		page = &scopes.Page{Number: max(findItemsInput.Page, 1), Size: findItemsInput.Limit}
+		if page.Size == 0 {
+			page.Size = defaultPageSize
+		}
+	}
+	if ranker, ok := s.rankers[findItemsInput.Sort]; ok {
+		items, meta, err := s.findRanked(query, page, ranker)
+		if err != nil {
+			return nil, err
+		}
+		return &ItemList{Meta: meta, each: func(fn func(items []models.Item) error) error {
+			return fn(*items)
+		}}, nil
+	}
+
+	query.Newest = true
+	list := &ItemList{}
+	if page != nil {
+		query.Page = page
+		total, err := s.repository.Count(query)
+		if err != nil {
+			return nil, err
+		}
+		pagination := dto.NewPaginationMeta(total, page.Number, page.Size)
+		list.Meta = &pagination
+	}
+	list.each = func(fn func(items []models.Item) error) error {
+		return s.repository.FindAllInBatches(query, streamBatchSize, func(items []models.Item) error {
+			if err := s.campaignService.Apply(items); err != nil {
+				return err
+			}
+			return fn(items)
+		})
+	}
+	return list, nil
+}
+
+// 点数はDBで付けられないため、条件に一致する新着の商品を並べ替えてからページを切り出す
+// 全件を読み込まないよう、候補はrankedCandidateLimit件までに絞る
+func (s *ItemService) findRanked(query repositories.ItemQuery, page *scopes.Page, ranker IItemRanker) (*[]models.Item, *dto.PaginationMeta, error) {
+	query.Newest = true
+	query.Limit = rankedCandidateLimit
+	items, err := s.repository.FindAll(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	// リポジトリが持っているスライスを並べ替えないようコピーする
+	// 割引中かどうかも評価に使うため、キャンペーンは並べ替える前に適用する
+	ranked := slices.Clone(*items)
+	if err := s.campaignService.Apply(ranked); err != nil {
+		return nil, nil, err
+	}
+	ranker.Rank(ranked)
+	if page == nil {
+		return &ranked, nil, nil
+	}
+	meta := dto.NewPaginationMeta(int64(len(ranked)), page.Number, page.Size)
+	size := min(max(page.Size, 1), scopes.MaxPageSize)
+	start := min((page.Number-1)*size, len(ranked))
+	ranked = ranked[start:min(start+size, len(ranked))]
+	return &ranked, &meta, nil
+}
+
+// 新着の商品を返す (フィード用)
+func (s *ItemService) FindRecent(categoryId *uint, limit int) (*[]models.Item, error) {
+	categoryIds, err := s.descendantCategoryIds(categoryId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repository.FindAll(repositories.ItemQuery{CategoryIds: categoryIds, Newest: true, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.campaignService.Apply(*items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// カテゴリで絞り込む場合は子孫カテゴリも含める (nilの場合は絞り込まない)
+func (s *ItemService) descendantCategoryIds(categoryId *uint) ([]uint, error) {
+	if categoryId == nil {
+		return nil, nil
+	}
+	categoryIds, err := s.categoryService.DescendantIds(*categoryId)
+	if err != nil {
+		if err.Error() == "Category not found" {
+			return nil, errors.New("Invalid category")
+		}
+		return nil, err
+	}
+	return categoryIds, nil
 }
 
 func (s *ItemService) FindById(itemId uint) (*models.Item, error) {
-	return s.repository.FindById(itemId)
+	item, err := s.repository.FindById(itemId)
+	if err != nil {
+		return nil, err
+	}
+	if item.CategoryID != nil {
+		breadcrumb, err := s.categoryService.Breadcrumb(*item.CategoryID)
+		if err != nil && err.Error() != "Category not found" {
+			return nil, err
+		}
+		item.Breadcrumb = breadcrumb
+	}
+	if err := s.applyCampaign(item); err != nil {
+		return nil, err
+	}
+	return item, nil
 }
 
 func (s *ItemService) Create(createItemInput dto.CreateItemInput) (*models.Item, error) {
+	if err := s.validateCategory(createItemInput.CategoryID); err != nil {
+		return nil, err
+	}
+	attributes := models.JSONMap(createItemInput.Attributes)
+	if err := s.categoryService.ValidateAttributes(createItemInput.CategoryID, attributes); err != nil {
+		return nil, err
+	}
+	metadata := models.JSONMap(createItemInput.Metadata)
+	if !matchSchema(metadataSchema, metadata) {
+		return nil, errors.New("Invalid metadata")
+	}
 	newItem := models.Item{
 		Name:        createItemInput.Name,
 		Price:       createItemInput.Price,
 		Description: createItemInput.Description,
 		SoldOut:     false,
+		CategoryID:  createItemInput.CategoryID,
+		Attributes:  attributes,
+		Metadata:    metadata,
+	}
+	createdItem, err := s.repository.Create(newItem)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCampaign(createdItem); err != nil {
+		return nil, err
 	}
-	return s.repository.Create(newItem)
+	return createdItem, nil
+}
+
+func (s *ItemService) Update(itemId uint, updateItemInput dto.UpdateItemInput, precondition ItemPrecondition) (*models.Item, error) {
+	return s.update(itemId, updateItemInput, false, precondition)
 }
 
-func (s *ItemService) Update(itemId uint, updateItemInput dto.UpdateItemInput) (*models.Item, error) {
+func (s *ItemService) Patch(itemId uint, updateItemInput dto.UpdateItemInput, precondition ItemPrecondition) (*models.Item, error) {
+	return s.update(itemId, updateItemInput, true, precondition)
+}
+
+// 書き込む商品を読み込み、条件を確認する
+func (s *ItemService) findForWrite(itemId uint, precondition ItemPrecondition) (*models.Item, error) {
 	targetItem, err := s.FindById(itemId)
 	if err != nil {
 		return nil, err
 	}
+	if precondition != nil && !precondition(targetItem) {
+		return nil, errors.New("Precondition failed")
+	}
+	return targetItem, nil
+}
+
+// 条件付きの場合は、findForWriteで読み込んだ時点から更新されていない場合だけ書き込む
+func (s *ItemService) save(targetItem models.Item, precondition ItemPrecondition) (*models.Item, error) {
+	if precondition == nil {
+		return s.repository.Update(targetItem)
+	}
+	return s.repository.UpdateIfUnmodified(targetItem, targetItem.UpdatedAt)
+}
+
+func (s *ItemService) update(itemId uint, updateItemInput dto.UpdateItemInput, mergeMetadata bool, precondition ItemPrecondition) (*models.Item, error) {
+	targetItem, err := s.findForWrite(itemId, precondition)
+	if err != nil {
+		return nil, err
+	}
 	if updateItemInput.Name != nil {
 		targetItem.Name = *updateItemInput.Name
 	}
@@ -56,10 +294,242 @@ func (s *ItemService) Update(itemId uint, updateItemInput dto.UpdateItemInput) (
 	}
 	if updateItemInput.SoldOut != nil {
 		targetItem.SoldOut = *updateItemInput.SoldOut
+		// 出品を再開した場合は終了理由を消す
+		if !targetItem.SoldOut {
+			targetItem.ClosureReason = ""
+			targetItem.ClosedAt = nil
+		}
 	}
-	return s.repository.Update(*targetItem)
+	if updateItemInput.CategoryID != nil {
+		if err := s.validateCategory(updateItemInput.CategoryID); err != nil {
+			return nil, err
+		}
+		targetItem.CategoryID = updateItemInput.CategoryID
+	}
+	if updateItemInput.Attributes != nil {
+		targetItem.Attributes = models.JSONMap(*updateItemInput.Attributes)
+	}
+	// カテゴリが変わった場合も含めて、最終的な属性を検証する
+	if updateItemInput.CategoryID != nil || updateItemInput.Attributes != nil {
+		if err := s.categoryService.ValidateAttributes(targetItem.CategoryID, targetItem.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	if updateItemInput.Metadata != nil {
+		if mergeMetadata {
+			targetItem.Metadata = mergeJSONMap(targetItem.Metadata, *updateItemInput.Metadata)
+		} else {
+			targetItem.Metadata = models.JSONMap(*updateItemInput.Metadata)
+		}
+		if !matchSchema(metadataSchema, targetItem.Metadata) {
+			return nil, errors.New("Invalid metadata")
+		}
+	}
+	if updateItemInput.ImageAltText != nil {
+		altText, err := normalizeAltText(*updateItemInput.ImageAltText)
+		if err != nil {
+			return nil, err
+		}
+		targetItem.ImageAltText = altText
+	}
+	updatedItem, err := s.save(*targetItem, precondition)
+	if err != nil {
+		return nil, err
+	}
+	// 価格やカテゴリが変わった場合に備えて、表示価格を計算し直す
+	if err := s.applyCampaign(updatedItem); err != nil {
+		return nil, err
+	}
+	return updatedItem, nil
+}
+
+// 1件の商品の表示価格にキャンペーンを反映する
+func (s *ItemService) applyCampaign(item *models.Item) error {
+	items := []models.Item{*item}
+	if err := s.campaignService.Apply(items); err != nil {
+		return err
+	}
+	item.DiscountedPrice = items[0].DiscountedPrice
+	item.CampaignID = items[0].CampaignID
+	return nil
+}
+
+// JSON Merge Patch (RFC 7396) と同様に、nullのキーは削除してそれ以外は上書きする
+func mergeJSONMap(base models.JSONMap, patch map[string]interface{}) models.JSONMap {
+	merged := models.JSONMap{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// クエリ文字列の値をmetadataの定義に合わせた型に変換する
+func parseMetadataFilter(filters map[string]string) (models.JSONMap, error) {
+	definitions := map[string]models.AttributeDefinition{}
+	for _, v := range metadataSchema {
+		definitions[v.Key] = v
+	}
+	metadata := models.JSONMap{}
+	for key, value := range filters {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, errors.New("Invalid metadata")
+		}
+		switch definition.Type {
+		case models.AttributeTypeNumber:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, errors.New("Invalid metadata")
+			}
+			metadata[key] = v
+		case models.AttributeTypeBoolean:
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.New("Invalid metadata")
+			}
+			metadata[key] = v
+		default:
+			metadata[key] = value
+		}
+	}
+	return metadata, nil
 }
 
-func (s *ItemService) Delete(itemId uint) error {
+// 保全中の商品は出品者でも削除できない
+func (s *ItemService) Delete(itemId uint, precondition ItemPrecondition) error {
+	targetItem, err := s.findForWrite(itemId, precondition)
+	if err != nil {
+		return err
+	}
+	if targetItem.LegalHold {
+		return errors.New("Item is under legal hold")
+	}
+	if precondition != nil {
+		return s.repository.DeleteIfUnmodified(itemId, targetItem.UpdatedAt)
+	}
 	return s.repository.Delete(itemId)
 }
+
+func (s *ItemService) SetLegalHold(itemId uint, legalHold bool) (*models.Item, error) {
+	targetItem, err := s.FindById(itemId)
+	if err != nil {
+		return nil, err
+	}
+	targetItem.LegalHold = legalHold
+	return s.repository.Update(*targetItem)
+}
+
+// プラットフォームでの取引とは区別して、終了理由を記録する
+func (s *ItemService) MarkSoldExternally(itemId uint, precondition ItemPrecondition) (*models.Item, error) {
+	targetItem, err := s.findForWrite(itemId, precondition)
+	if err != nil {
+		return nil, err
+	}
+	if targetItem.SoldOut {
+		return nil, errors.New("Item already sold out")
+	}
+	closedAt := s.clock.Now()
+	targetItem.SoldOut = true
+	targetItem.ClosureReason = models.ClosureReasonSoldExternally
+	targetItem.ClosedAt = &closedAt
+	return s.save(*targetItem, precondition)
+}
+
+func (s *ItemService) validateCategory(categoryId *uint) error {
+	if categoryId == nil {
+		return nil
+	}
+	if _, err := s.categoryService.FindById(*categoryId); err != nil {
+		if err.Error() == "Category not found" {
+			return errors.New("Invalid category")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ItemService) SetImage(itemId uint, image io.Reader, altText string) (*models.Item, error) {
+	targetItem, err := s.FindById(itemId)
+	if err != nil {
+		return nil, err
+	}
+	altText, err = normalizeAltText(altText)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.vision.Hash(image)
+	if err != nil {
+		if err.Error() == "Image too large" {
+			return nil, err
+		}
+		return nil, errors.New("Invalid image")
+	}
+	targetItem.ImageHash = hash
+	targetItem.ImageAltText = altText
+	return s.repository.Update(*targetItem)
+}
+
+// 前後の空白を除き、長すぎる代替テキストや改行を含むものは拒否する
+func normalizeAltText(altText string) (string, error) {
+	altText = strings.TrimSpace(altText)
+	if utf8.RuneCountInString(altText) > models.MaxImageAltTextLength || strings.ContainsAny(altText, "\r\n") {
+		return "", errors.New("Invalid alt text")
+	}
+	return altText, nil
+}
+
+func (s *ItemService) SearchByImage(image io.Reader) (*[]models.Item, error) {
+	hash, err := s.vision.Hash(image)
+	if err != nil {
+		if err.Error() == "Image too large" {
+			return nil, err
+		}
+		return nil, errors.New("Invalid image")
+	}
+	candidates, err := s.repository.FindWithImageHash()
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		item     models.Item
+		distance int
+	}
+	matches := []match{}
+	for _, v := range *candidates {
+		distance, err := s.vision.Distance(hash, v.ImageHash)
+		if err != nil || distance > similarImageDistance {
+			continue
+		}
+		matches = append(matches, match{item: v, distance: distance})
+	}
+	// 似ている順に並べる
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	items := make([]models.Item, 0, len(matches))
+	for _, m := range matches {
+		items = append(items, m.item)
+	}
+	if err := s.campaignService.Apply(items); err != nil {
+		return nil, err
+	}
+	return &items, nil
+}
+
+func (s *ItemService) Stream(streamItemsInput dto.StreamItemsInput, fn func(items []models.Item) error) error {
+	return s.repository.FindInBatches(streamItemsInput.Since, streamBatchSize, func(items []models.Item) error {
+		if err := s.campaignService.Apply(items); err != nil {
+			return err
+		}
+		return fn(items)
+	})
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bytes"
+	"gin-fleamarket/dto"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"gin-fleamarket/testutil/factory"
+	"gin-fleamarket/testutil/testdb"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestItemService(t *testing.T) (*ItemService, *gorm.DB) {
+	t.Helper()
+	db := testdb.Open(t)
+	clock := infra.NewFakeClock(testNow)
+	categoryService := NewCategoryService(repositories.NewCategoryRepository(db))
+	campaignService := NewCampaignService(repositories.NewCampaignRepository(db), categoryService, clock)
+	service := NewItemService(repositories.NewItemRepository(db), categoryService, campaignService, infra.NewVisionProvider(), clock).(*ItemService)
+	return service, db
+}
+
+// 条件を確認してから書き込むまでの間に、別の書き込みが入った場合
+func TestItemServiceConditionalWriteWithConcurrentWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(s *ItemService, itemId uint, precondition ItemPrecondition) error
+	}{
+		{name: "update", write: func(s *ItemService, itemId uint, precondition ItemPrecondition) error {
+			name := "最初の書き込み"
+			_, err := s.Update(itemId, dto.UpdateItemInput{Name: &name}, precondition)
+			return err
+		}},
+		{name: "patch", write: func(s *ItemService, itemId uint, precondition ItemPrecondition) error {
+			price := uint(2000)
+			_, err := s.Patch(itemId, dto.UpdateItemInput{Price: &price}, precondition)
+			return err
+		}},
+		{name: "mark sold externally", write: func(s *ItemService, itemId uint, precondition ItemPrecondition) error {
+			_, err := s.MarkSoldExternally(itemId, precondition)
+			return err
+		}},
+		{name: "delete", write: func(s *ItemService, itemId uint, precondition ItemPrecondition) error {
+			return s.Delete(itemId, precondition)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestItemService(t)
+			item := factory.NewItemFactory(db).Create(t)
+
+			// 条件を満たしたと判断した直後に、別の書き込みが先に完了する
+			concurrent := "割り込んだ書き込み"
+			precondition := func(item *models.Item) bool {
+				if _, err := service.Update(item.ID, dto.UpdateItemInput{Description: &concurrent}, nil); err != nil {
+					t.Fatalf("concurrent Update() error = %v", err)
+				}
+				return true
+			}
+			if err := tt.write(service, item.ID, precondition); err == nil || err.Error() != "Precondition failed" {
+				t.Fatalf("write error = %v, want Precondition failed", err)
+			}
+
+			// 割り込んだ書き込みが残り、条件付きの書き込みは反映されない
+			current, err := service.FindById(item.ID)
+			if err != nil {
+				t.Fatalf("FindById() error = %v", err)
+			}
+			if current.Description != concurrent || current.Name != item.Name || current.Price != item.Price || current.SoldOut {
+				t.Errorf("item = %+v, want only the concurrent write", current)
+			}
+		})
+	}
+}
+
+func TestItemServiceConditionalWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		met     bool
+		wantErr string
+	}{
+		{name: "precondition met", met: true},
+		{name: "precondition not met", met: false, wantErr: "Precondition failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestItemService(t)
+			item := factory.NewItemFactory(db).Create(t)
+			name := "新しい名前"
+			updated, err := service.Update(item.ID, dto.UpdateItemInput{Name: &name}, func(*models.Item) bool { return tt.met })
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Update() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if updated.Name != name {
+				t.Errorf("Name = %q, want %q", updated.Name, name)
+			}
+			// 続けて条件付きで書き込めるよう、更新日時は保存した値と一致する
+			current, err := service.FindById(item.ID)
+			if err != nil {
+				t.Fatalf("FindById() error = %v", err)
+			}
+			if !current.UpdatedAt.Equal(updated.UpdatedAt) || !current.UpdatedAt.After(item.UpdatedAt) {
+				t.Errorf("UpdatedAt = %v, saved %v, before %v", updated.UpdatedAt, current.UpdatedAt, item.UpdatedAt)
+			}
+		})
+	}
+}
+
+// 一覧以外の経路でも、開催中のキャンペーンを表示価格に反映する
+func TestItemServiceAppliesCampaigns(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	hash, err := infra.NewVisionProvider().Hash(bytes.NewReader(img.Bytes()))
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		find func(s *ItemService) ([]models.Item, error)
+	}{
+		{name: "search by image", find: func(s *ItemService) ([]models.Item, error) {
+			items, err := s.SearchByImage(bytes.NewReader(img.Bytes()))
+			if err != nil {
+				return nil, err
+			}
+			return *items, nil
+		}},
+		{name: "stream", find: func(s *ItemService) ([]models.Item, error) {
+			streamed := []models.Item{}
+			err := s.Stream(dto.StreamItemsInput{}, func(items []models.Item) error {
+				streamed = append(streamed, items...)
+				return nil
+			})
+			return streamed, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, db := newTestItemService(t)
+			category := factory.NewCategoryFactory(db).Create(t, nil)
+			factory.NewItemFactory(db).Create(t, func(i *models.Item) {
+				i.Price = 1000
+				i.CategoryID = &category.ID
+				i.ImageHash = hash
+			})
+			campaign := models.Campaign{
+				Name:            "春のセール",
+				StartsAt:        testNow.Add(-24 * time.Hour),
+				EndsAt:          testNow.Add(24 * time.Hour),
+				CategoryID:      &category.ID,
+				DiscountPercent: 10,
+			}
+			if err := db.Create(&campaign).Error; err != nil {
+				t.Fatalf("failed to create campaign: %v", err)
+			}
+
+			items, err := tt.find(service)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("len(items) = %d, want 1", len(items))
+			}
+			if got := items[0].DiscountedPrice; got == nil || *got != 900 {
+				t.Errorf("DiscountedPrice = %v, want 900", got)
+			}
+			if got := items[0].CampaignID; got == nil || *got != campaign.ID {
+				t.Errorf("CampaignID = %v, want %d", got, campaign.ID)
+			}
+		})
+	}
+}
+
+// 渡された条件を記録するリポジトリ
+type recordingItemRepository struct {
+	repositories.IItemRepository
+	queries []repositories.ItemQuery
+}
+
+func (r *recordingItemRepository) FindAll(query repositories.ItemQuery) (*[]models.Item, error) {
+	r.queries = append(r.queries, query)
+	return r.IItemRepository.FindAll(query)
+}
+
+// おすすめ順でも、並べ替える候補は新着の一定件数までに絞る
+func TestItemServiceFindRankedBoundsCandidates(t *testing.T) {
+	items := make([]models.Item, 0, rankedCandidateLimit+10)
+	for i := range rankedCandidateLimit + 10 {
+		items = append(items, factory.BuildItem(func(item *models.Item) {
+			item.ID = uint(i + 1)
+			item.CreatedAt = testNow.Add(time.Duration(i) * time.Minute)
+		}))
+	}
+	repository := &recordingItemRepository{IItemRepository: repositories.NewItemMemoryRepository(items)}
+	clock := infra.NewFakeClock(testNow)
+	categoryService := NewCategoryService(repositories.NewCategoryRepository(testdb.Open(t)))
+	campaignService := NewCampaignService(&fakeCampaignRepository{}, categoryService, clock)
+	service := NewItemService(repository, categoryService, campaignService, infra.NewVisionProvider(), clock)
+
+	list, err := service.FindAll(dto.FindItemsInput{Sort: dto.SortRecommended})
+	if err != nil {
+		t.Fatalf("FindAll() error = %v", err)
+	}
+	ranked := []models.Item{}
+	if err := list.Each(func(items []models.Item) error {
+		ranked = append(ranked, items...)
+		return nil
+	}); err != nil {
+		t.Fatalf("Each() error = %v", err)
+	}
+
+	if len(repository.queries) != 1 {
+		t.Fatalf("FindAll called %d times, want 1", len(repository.queries))
+	}
+	if query := repository.queries[0]; !query.Newest || query.Limit != rankedCandidateLimit {
+		t.Errorf("query Newest = %v, Limit = %d, want true, %d", query.Newest, query.Limit, rankedCandidateLimit)
+	}
+	if len(ranked) != rankedCandidateLimit {
+		t.Errorf("len(ranked) = %d, want %d", len(ranked), rankedCandidateLimit)
+	}
+	for _, v := range ranked {
+		if v.ID <= 10 {
+			t.Fatalf("item %d is older than the candidates", v.ID)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"gin-fleamarket/infra"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"net/url"
+)
+
+// 短縮URLのコードの長さ (URLセーフな文字で48bit)
+const shortLinkCodeLength = 8
+
+// コードが衝突した場合の作り直しの上限
+const shortLinkMaxAttempts = 3
+
+// 商品の共有状況
+type ShareStats struct {
+	Code      string                       `json:"code"`
+	Clicks    uint                         `json:"clicks"`
+	Referrers []repositories.ReferrerCount `json:"referrers"`
+}
+
+type IShortLinkService interface {
+	FindOrCreate(itemId uint) (*models.ShortLink, bool, error)
+	// 作成済みの短縮URLを返す (作成はしない)
+	FindByItemId(itemId uint) (*models.ShortLink, error)
+	Resolve(code string, referrer string) (*models.ShortLink, error)
+	Stats(itemId uint) (*ShareStats, error)
+}
+
+type ShortLinkService struct {
+	repository  repositories.IShortLinkRepository
+	itemService IItemService
+	generator   infra.ITokenGenerator
+}
+
+func NewShortLinkService(repository repositories.IShortLinkRepository, itemService IItemService, generator infra.ITokenGenerator) IShortLinkService {
+	return &ShortLinkService{repository: repository, itemService: itemService, generator: generator}
+}
+
+// 商品ごとに1つの短縮URLを使い回す (作成した場合はtrue)
+func (s *ShortLinkService) FindOrCreate(itemId uint) (*models.ShortLink, bool, error) {
+	if _, err := s.itemService.FindById(itemId); err != nil {
+		return nil, false, err
+	}
+	shortLink, err := s.repository.FindByItemId(itemId)
+	if err == nil {
+		return shortLink, false, nil
+	}
+	if err.Error() != "Short link not found" {
+		return nil, false, err
+	}
+
+	for i := 0; i < shortLinkMaxAttempts; i++ {
+		code := s.generator.Token()[:shortLinkCodeLength]
+		if _, err := s.repository.FindByCode(code); err == nil {
+			continue
+		}
+		shortLink, err := s.repository.Create(models.ShortLink{Code: code, ItemID: itemId})
+		if err != nil {
+			// 同時に作成された場合はそちらを返す
+			if existing, findErr := s.repository.FindByItemId(itemId); findErr == nil {
+				return existing, false, nil
+			}
+			return nil, false, err
+		}
+		return shortLink, true, nil
+	}
+	return nil, false, errors.New("Failed to generate short link")
+}
+
+// 商品の作成済みの短縮URLを返す (作成はしない)
+func (s *ShortLinkService) FindByItemId(itemId uint) (*models.ShortLink, error) {
+	if _, err := s.itemService.FindById(itemId); err != nil {
+		return nil, err
+	}
+	return s.repository.FindByItemId(itemId)
+}
+
+// コードから短縮URLを引き、アクセスを記録する
+func (s *ShortLinkService) Resolve(code string, referrer string) (*models.ShortLink, error) {
+	shortLink, err := s.repository.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.RecordClick(*shortLink, referrerHost(referrer)); err != nil {
+		return nil, err
+	}
+	return shortLink, nil
+}
+
+func (s *ShortLinkService) Stats(itemId uint) (*ShareStats, error) {
+	if _, err := s.itemService.FindById(itemId); err != nil {
+		return nil, err
+	}
+	shortLink, err := s.repository.FindByItemId(itemId)
+	if err != nil {
+		// 共有されていない商品は0件として返す
+		if err.Error() == "Short link not found" {
+			return &ShareStats{Referrers: []repositories.ReferrerCount{}}, nil
+		}
+		return nil, err
+	}
+	referrers, err := s.repository.CountByReferrer(shortLink.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ShareStats{Code: shortLink.Code, Clicks: shortLink.Clicks, Referrers: referrers}, nil
+}
+
+// 参照元はホスト名だけを記録する (パスやクエリに個人情報が含まれうるため)
+func referrerHost(referrer string) string {
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,131 @@
+// テストデータを手軽に作るためのファクトリー
+// Buildは保存せずに値を返し、CreateはDBに保存して返す
+// DBを使わないテストでは、BuildItemなどで値だけを作る
+// 例: item := factory.NewItemFactory(db).Create(t, func(i *models.Item) { i.Price = 500 })
+
+package factory
+
+import (
+	"fmt"
+	"gin-fleamarket/models"
+	"gin-fleamarket/repositories"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// 名前やメールアドレスを一意にするための連番
+var sequence atomic.Int64
+
+func next() int64 {
+	return sequence.Add(1)
+}
+
+type ItemFactory struct {
+	db *gorm.DB
+}
+
+func NewItemFactory(db *gorm.DB) *ItemFactory {
+	return &ItemFactory{db: db}
+}
+
+func (f *ItemFactory) Build(overrides ...func(item *models.Item)) models.Item {
+	return BuildItem(overrides...)
+}
+
+func BuildItem(overrides ...func(item *models.Item)) models.Item {
+	n := next()
+	item := models.Item{
+		Name:        fmt.Sprintf("Item%d", n),
+		Price:       1000,
+		Description: fmt.Sprintf("Description%d", n),
+		SoldOut:     false,
+		Attributes:  models.JSONMap{},
+		Metadata:    models.JSONMap{},
+	}
+	for _, override := range overrides {
+		override(&item)
+	}
+	return item
+}
+
+func (f *ItemFactory) Create(t testing.TB, overrides ...func(item *models.Item)) *models.Item {
+	t.Helper()
+	item := f.Build(overrides...)
+	if err := f.db.Create(&item).Error; err != nil {
+		t.Fatalf("failed to create item: %v", err)
+	}
+	return &item
+}
+
+type UserFactory struct {
+	db *gorm.DB
+}
+
+func NewUserFactory(db *gorm.DB) *UserFactory {
+	return &UserFactory{db: db}
+}
+
+func (f *UserFactory) Build(overrides ...func(user *models.User)) models.User {
+	return BuildUser(overrides...)
+}
+
+func BuildUser(overrides ...func(user *models.User)) models.User {
+	n := next()
+	user := models.User{
+		Email:    fmt.Sprintf("user%d@example.com", n),
+		Password: "password",
+	}
+	for _, override := range overrides {
+		override(&user)
+	}
+	return user
+}
+
+func (f *UserFactory) Create(t testing.TB, overrides ...func(user *models.User)) *models.User {
+	t.Helper()
+	user := f.Build(overrides...)
+	if err := f.db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return &user
+}
+
+type CategoryFactory struct {
+	db *gorm.DB
+}
+
+func NewCategoryFactory(db *gorm.DB) *CategoryFactory {
+	return &CategoryFactory{db: db}
+}
+
+func (f *CategoryFactory) Build(overrides ...func(category *models.Category)) models.Category {
+	return BuildCategory(overrides...)
+}
+
+func BuildCategory(overrides ...func(category *models.Category)) models.Category {
+	category := models.Category{
+		Name:            fmt.Sprintf("Category%d", next()),
+		AttributeSchema: models.AttributeSchema{},
+	}
+	for _, override := range overrides {
+		override(&category)
+	}
+	return category
+}
+
+// parentを指定すると、その子カテゴリとして保存する
+// パスはアプリケーションと同じく、リポジトリのCreateで設定する
+func (f *CategoryFactory) Create(t testing.TB, parent *models.Category, overrides ...func(category *models.Category)) *models.Category {
+	t.Helper()
+	category := f.Build(overrides...)
+	if parent != nil {
+		category.ParentID = &parent.ID
+	}
+	created, err := repositories.NewCategoryRepository(f.db).Create(category, parent)
+	if err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	return created
+}
@@ -0,0 +1,89 @@
+// APIレスポンスをゴールデンファイルと比較するためのスナップショットテスト用ヘルパー
+// UPDATE_GOLDEN=1 を付けて実行するとゴールデンファイルを書き換える
+// 例: golden.AssertJSON(t, "find_item", w.Body.Bytes(), "createdAt", "updatedAt")
+
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ゴールデンファイルの置き場所（テストを実行するパッケージからの相対パス）
+const dir = "testdata/golden"
+
+// 時刻やIDなど実行ごとに変わる値を置き換える文字列
+const scrubbed = "<scrubbed>"
+
+// bodyを正規化したJSONに変換してゴールデンファイルと比較する
+// scrubKeysに指定したキーの値は、どの階層にあっても固定の文字列に置き換える
+func AssertJSON(t testing.TB, name string, body []byte, scrubKeys ...string) {
+	t.Helper()
+	got, err := Canonicalize(body, scrubKeys...)
+	if err != nil {
+		t.Fatalf("golden %s: invalid JSON: %v\n%s", name, err, body)
+	}
+
+	path := filepath.Join(dir, name+".golden.json")
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("golden %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden %s: %v", name, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden %s: %v (run with UPDATE_GOLDEN=1 to create it)", name, err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("golden %s: response does not match %s (run with UPDATE_GOLDEN=1 if the change is intended)\n--- want\n%s\n--- got\n%s", name, path, want, got)
+	}
+}
+
+// キーをソートし、インデントを揃えたJSONを返す
+func Canonicalize(body []byte, scrubKeys ...string) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(scrubKeys))
+	for _, k := range scrubKeys {
+		keys[k] = true
+	}
+	v = scrub(v, keys)
+
+	// encoding/jsonはmapのキーをソートして出力する
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func scrub(v interface{}, keys map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if keys[k] && child != nil {
+				v[k] = scrubbed
+				continue
+			}
+			v[k] = scrub(child, keys)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = scrub(child, keys)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,61 @@
+// テスト用のDB
+// sqliteのインメモリDBを使うため、Postgresがなくても実行でき、テストごとに空のDBになる
+// 例: db := testdb.Open(t)
+
+package testdb
+
+import (
+	"fmt"
+	"gin-fleamarket/models"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 同じプロセスで開いたDBを区別するための連番
+var sequence atomic.Int64
+
+// テスト対象のテーブル (migrationsのAutoMigrateと揃える)
+var tables = []interface{}{
+	&models.Category{},
+	&models.Item{},
+	&models.User{},
+	&models.Campaign{},
+	&models.ShortLink{},
+	&models.ShortLinkClick{},
+	&models.CategoryItemCount{},
+	&models.CategoryAlias{},
+	&models.FeatureUsage{},
+}
+
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	// 接続ごとに別のDBにならないよう、名前を付けて共有する
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared&_foreign_keys=1", sequence.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, table := range tables {
+		// sqliteにはGINインデックスがないため、items.metadataには通常のインデックスを作る
+		// (テーブルは作成済み。作っておかないと、itemsに関連するテーブルの作成でも同じエラーになる)
+		err := db.AutoMigrate(table)
+		if err != nil && strings.Contains(err.Error(), `near "USING"`) {
+			err = db.Exec("CREATE INDEX idx_items_metadata ON items (metadata)").Error
+		}
+		if err != nil {
+			t.Fatalf("failed to migrate test database: %v", err)
+		}
+	}
+	return db
+}